@@ -0,0 +1,175 @@
+package raven
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// An Attachment is a file sent along with an event, such as a small JSON
+// document or a snippet of a log.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Bytes       []byte
+}
+
+const (
+	defaultMaxAttachmentSize  = 1 << 20
+	defaultMaxAttachmentsSize = 10 << 20
+)
+
+// envelopeContentType is the content type of the requests sent to the
+// envelope endpoint.
+const envelopeContentType = "application/x-sentry-envelope"
+
+// limitAttachments drops the attachments of ev which are larger than the
+// client's MaxAttachmentSize, and those which would take the total past its
+// MaxAttachmentsSize.
+func (client *Client) limitAttachments(ev *Event) {
+	if len(ev.Attachments) == 0 {
+		return
+	}
+	maxSize, maxTotal := client.MaxAttachmentSize, client.MaxAttachmentsSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxAttachmentSize
+	}
+	if maxTotal <= 0 {
+		maxTotal = defaultMaxAttachmentsSize
+	}
+
+	// The slice may be shared with the caller, so a new one is made
+	var kept []Attachment
+	total := 0
+	for _, a := range ev.Attachments {
+		if len(a.Bytes) > maxSize || total+len(a.Bytes) > maxTotal {
+			continue
+		}
+		total += len(a.Bytes)
+		kept = append(kept, a)
+	}
+	ev.Attachments = kept
+}
+
+// envelopeHeader is the first line of an envelope.
+type envelopeHeader struct {
+	EventId string `json:"event_id"`
+	SentAt  string `json:"sent_at"`
+}
+
+// envelopeItemHeader precedes each item of an envelope.
+type envelopeItemHeader struct {
+	Type        string `json:"type"`
+	Length      int    `json:"length"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// encodeEnvelope encodes ev and its attachments in Sentry's envelope format:
+// a header line followed by an item for the event and one for each
+// attachment, each of which is a header line and its payload.
+func encodeEnvelope(ev *Event, sentAt time.Time) ([]byte, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("encoding the event: %w", err)
+	}
+
+	var buf bytes.Buffer
+	writeItem := func(header interface{}, payload []byte) error {
+		h, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+		buf.Write(h)
+		buf.WriteByte('\n')
+		if payload != nil {
+			buf.Write(payload)
+			buf.WriteByte('\n')
+		}
+		return nil
+	}
+	header := envelopeHeader{EventId: ev.EventId, SentAt: sentAt.UTC().Format(time.RFC3339)}
+	if err := writeItem(header, nil); err != nil {
+		return nil, err
+	}
+	if err := writeItem(envelopeItemHeader{Type: "event", Length: len(payload)}, payload); err != nil {
+		return nil, err
+	}
+	for _, a := range ev.Attachments {
+		item := envelopeItemHeader{
+			Type:        "attachment",
+			Length:      len(a.Bytes),
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+		}
+		if err := writeItem(item, a.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// isEnvelope reports whether packet is an envelope rather than an event
+// encoded by an Encoder, which is either base64 or gzipped.
+func isEnvelope(packet []byte) bool {
+	return len(packet) > 0 && packet[0] == '{'
+}
+
+// DecodeEnvelope decodes an event and its attachments sent in Sentry's
+// envelope format, as events with attachments are. Items other than the event
+// and its attachments are skipped.
+func DecodeEnvelope(r io.Reader) (*Event, []Attachment, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	var header envelopeHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, nil, fmt.Errorf("decoding the envelope header: %v", err)
+	}
+
+	var ev *Event
+	var attachments []Attachment
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(bytes.TrimSpace(line)) == 0 {
+			break
+		} else if err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		var item envelopeItemHeader
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, nil, fmt.Errorf("decoding an item header: %v", err)
+		}
+		payload := make([]byte, item.Length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return nil, nil, fmt.Errorf("reading the %s item: %v", item.Type, err)
+		}
+		// The payload may be followed by a newline
+		if b, err := br.Peek(1); err == nil && b[0] == '\n' {
+			br.ReadByte()
+		}
+
+		switch item.Type {
+		case "event":
+			ev = new(Event)
+			if err := json.Unmarshal(payload, ev); err != nil {
+				return nil, nil, fmt.Errorf("decoding the event: %v", err)
+			}
+		case "attachment":
+			attachments = append(attachments, Attachment{
+				Filename:    item.Filename,
+				ContentType: item.ContentType,
+				Bytes:       payload,
+			})
+		}
+	}
+	if ev == nil {
+		return nil, nil, fmt.Errorf("the envelope of %s has no event", header.EventId)
+	}
+	return ev, attachments, nil
+}
@@ -0,0 +1,51 @@
+package raven
+
+import (
+	"context"
+	"fmt"
+)
+
+// CaptureBatch sends several events to Sentry, filling in their blank fields,
+// routing and sampling them, limiting their rate and suppressing repeats as
+// Capture does.
+//
+// The store endpoint accepts a single event per request, so the events are
+// sent one after another, reusing the client's connection unless
+// CloseConnections is set. There is no limit on the number of events in a
+// batch, but each event is still subject to the server's limit on the size of
+// an event.
+//
+// Events which fail are not retried. Their errors, each naming the id of its
+// event, are returned in a MultiError once the whole batch has been sent.
+func (client *Client) CaptureBatch(events []*Event) error {
+	_, err := client.CaptureBatchContext(context.Background(), events)
+	return err
+}
+
+// CaptureBatchContext sends several events like CaptureBatch, within the
+// deadline of ctx, which also bounds each request. Once ctx is done no more
+// events are sent: those left are returned, and ctx's error is added to the
+// MultiError of the events which failed. An event whose request was cut short
+// by ctx is not among those returned, as it may have been added to the
+// client's spool, but its error is in the MultiError.
+func (client *Client) CaptureBatchContext(ctx context.Context, events []*Event) (unsent []*Event, err error) {
+	var errs MultiError
+	for i, ev := range events {
+		if ctx.Err() != nil {
+			unsent = events[i:]
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if _, err := client.capture(ctx, ev, nil); err != nil {
+			if ev.EventId == "" {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, fmt.Errorf("event %s: %v", ev.EventId, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return unsent, errs
+	}
+	return unsent, nil
+}
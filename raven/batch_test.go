@@ -0,0 +1,124 @@
+package raven
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCaptureBatch(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	connections := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			ev, err := DecodeEvent(req.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			received = append(received, ev.Message)
+			mu.Unlock()
+			fmt.Fprint(w, "hello")
+		}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+	client := GetClient(server)
+
+	var events []*Event
+	for i := 0; i < 5; i++ {
+		events = append(events, &Event{Message: fmt.Sprint("batch ", i)})
+	}
+	if err := client.CaptureBatch(events); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != len(events) {
+		t.Fatalf("server received %d events, want %d", len(received), len(events))
+	}
+	for i, msg := range received {
+		if want := fmt.Sprint("batch ", i); msg != want {
+			t.Errorf("event %d: got %s, want %s", i, msg, want)
+		}
+		if events[i].EventId == "" {
+			t.Errorf("event %d has no EventId", i)
+		}
+	}
+	if connections != 1 {
+		t.Errorf("batch used %d connections, want 1", connections)
+	}
+}
+
+func TestCaptureBatchErrors(t *testing.T) {
+	server := newFailingServer()
+	defer server.Close()
+	client := GetClient(server)
+
+	events := []*Event{{Message: "one"}, {Message: "two"}}
+	err := client.CaptureBatch(events)
+	merr, ok := err.(MultiError)
+	if !ok {
+		t.Fatalf("got %v, want a MultiError", err)
+	}
+	if len(merr) != len(events) {
+		t.Errorf("got %d errors, want %d", len(merr), len(events))
+	}
+}
+
+func TestCaptureBatchContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(40 * time.Millisecond)
+			fmt.Fprint(w, "hello")
+		}))
+	defer server.Close()
+	client := GetClient(server)
+
+	var events []*Event
+	for i := 0; i < 10; i++ {
+		events = append(events, &Event{Message: fmt.Sprint("batch ", i)})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	unsent, err := client.CaptureBatchContext(ctx, events)
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("the batch took %s, the deadline was not used", elapsed)
+	}
+	merr, ok := err.(MultiError)
+	if !ok || !errors.Is(merr[len(merr)-1], context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a MultiError ending with the deadline", err)
+	}
+	if len(unsent) == 0 || len(unsent) >= len(events) {
+		t.Fatalf("got %d unsent events of %d", len(unsent), len(events))
+	}
+	if unsent[len(unsent)-1] != events[len(events)-1] {
+		t.Error("the unsent events should be the last of the batch")
+	}
+	for _, ev := range unsent {
+		if ev.EventId != "" {
+			t.Errorf("unsent event %q was prepared", ev.Message)
+		}
+	}
+
+	unsent, err = client.CaptureBatchContext(context.Background(), events[:2])
+	if err != nil || unsent != nil {
+		t.Errorf("got %v, %v for a batch without a deadline", unsent, err)
+	}
+}
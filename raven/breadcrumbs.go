@@ -0,0 +1,129 @@
+package raven
+
+// A Breadcrumb records something which happened before an event was captured,
+// such as a log message or an outgoing request.
+type Breadcrumb struct {
+	Timestamp string                 `json:"timestamp"`
+	Type      string                 `json:"type,omitempty"`
+	Category  string                 `json:"category,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Level     Severity               `json:"level,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// breadcrumbValues is the wire format of the breadcrumbs interface.
+type breadcrumbValues struct {
+	Values []Breadcrumb `json:"values"`
+}
+
+// AddBreadcrumb records a breadcrumb to be attached to subsequently captured events.
+// If the breadcrumb has no timestamp the current time is used.
+func (client *Client) AddBreadcrumb(b Breadcrumb) {
+	if b.Timestamp == "" {
+		b.Timestamp = client.now().UTC().Format(iso8601)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.breadcrumbs.resize(client.MaxBreadcrumbs)
+	client.breadcrumbs.add(b)
+}
+
+// AddBreadcrumbs records several breadcrumbs at once, in order. Breadcrumbs
+// added by other goroutines are never interleaved with them. Breadcrumbs
+// without timestamps are given the current time.
+func (client *Client) AddBreadcrumbs(bs ...Breadcrumb) {
+	if len(bs) == 0 {
+		return
+	}
+	now := client.now().UTC().Format(iso8601)
+	bs = append([]Breadcrumb(nil), bs...)
+	for i := range bs {
+		if bs[i].Timestamp == "" {
+			bs[i].Timestamp = now
+		}
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.breadcrumbs.resize(client.MaxBreadcrumbs)
+	client.breadcrumbs.addAll(bs)
+}
+
+// Breadcrumbs returns the recorded breadcrumbs, oldest first.
+func (client *Client) Breadcrumbs() []Breadcrumb {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.breadcrumbs.resize(client.MaxBreadcrumbs)
+	return client.breadcrumbs.list()
+}
+
+// breadcrumbRing is a fixed capacity buffer which evicts its oldest element
+// when full.
+type breadcrumbRing struct {
+	items []Breadcrumb
+	start int // index of the oldest item
+	n     int // number of items held
+}
+
+func (r *breadcrumbRing) add(b Breadcrumb) {
+	if len(r.items) == 0 {
+		return
+	}
+	i := (r.start + r.n) % len(r.items)
+	r.items[i] = b
+	if r.n < len(r.items) {
+		r.n++
+	} else {
+		r.start = (r.start + 1) % len(r.items)
+	}
+}
+
+// addAll adds bs in order, as repeated calls to add would, but evicts the
+// oldest items only once.
+func (r *breadcrumbRing) addAll(bs []Breadcrumb) {
+	size := len(r.items)
+	if size == 0 {
+		return
+	}
+	if len(bs) > size {
+		bs = bs[len(bs)-size:]
+	}
+	for i, b := range bs {
+		r.items[(r.start+r.n+i)%size] = b
+	}
+	if over := r.n + len(bs) - size; over > 0 {
+		r.start = (r.start + over) % size
+		r.n = size
+	} else {
+		r.n += len(bs)
+	}
+}
+
+func (r *breadcrumbRing) list() []Breadcrumb {
+	if r.n == 0 {
+		return nil
+	}
+	bs := make([]Breadcrumb, r.n)
+	for i := range bs {
+		bs[i] = r.items[(r.start+i)%len(r.items)]
+	}
+	return bs
+}
+
+// resize changes the capacity of the ring, keeping the newest items.
+func (r *breadcrumbRing) resize(capacity int) {
+	if capacity < 0 {
+		capacity = 0
+	}
+	if capacity == len(r.items) {
+		return
+	}
+	bs := r.list()
+	if len(bs) > capacity {
+		bs = bs[len(bs)-capacity:]
+	}
+	r.items = make([]Breadcrumb, capacity)
+	r.start = 0
+	r.n = copy(r.items, bs)
+}
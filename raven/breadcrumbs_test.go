@@ -0,0 +1,149 @@
+package raven
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestMaxBreadcrumbs(t *testing.T) {
+	client := &Client{MaxBreadcrumbs: 3}
+	for i := 0; i < 5; i++ {
+		client.AddBreadcrumb(Breadcrumb{Message: fmt.Sprint(i)})
+	}
+
+	bs := client.Breadcrumbs()
+	if len(bs) != 3 {
+		t.Fatalf("got %d breadcrumbs, want 3", len(bs))
+	}
+	for i, b := range bs {
+		if want := fmt.Sprint(i + 2); b.Message != want {
+			t.Errorf("breadcrumb %d: got %s, want %s", i, b.Message, want)
+		}
+		if b.Timestamp == "" {
+			t.Errorf("breadcrumb %d has no timestamp", i)
+		}
+	}
+
+	// Shrinking the buffer keeps the newest breadcrumbs
+	client.MaxBreadcrumbs = 1
+	if bs := client.Breadcrumbs(); len(bs) != 1 || bs[0].Message != "4" {
+		t.Errorf("after shrinking: got %v, want only breadcrumb 4", bs)
+	}
+}
+
+func TestBreadcrumbsDisabled(t *testing.T) {
+	client := &Client{}
+	client.AddBreadcrumb(Breadcrumb{Message: "dropped"})
+	if bs := client.Breadcrumbs(); len(bs) != 0 {
+		t.Errorf("got %v, want no breadcrumbs", bs)
+	}
+}
+
+func TestBreadcrumbsConcurrent(t *testing.T) {
+	client := &Client{MaxBreadcrumbs: 10}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				client.AddBreadcrumb(Breadcrumb{Message: "concurrent"})
+			}
+		}()
+	}
+	wg.Wait()
+	if bs := client.Breadcrumbs(); len(bs) != 10 {
+		t.Errorf("got %d breadcrumbs, want 10", len(bs))
+	}
+}
+
+func TestBreadcrumbsAttached(t *testing.T) {
+	var capturedEvent *Event
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, "hello")
+			capturedEvent, _ = DecodeEvent(req.Body)
+		}))
+	defer server.Close()
+	client := GetClient(server)
+
+	client.AddBreadcrumb(Breadcrumb{Category: "auth", Message: "user logged in"})
+	if _, err := client.CaptureMessage("test message"); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := capturedEvent.Breadcrumbs
+	if len(bs) != 1 || bs[0].Category != "auth" || bs[0].Message != "user logged in" {
+		t.Errorf("bad breadcrumbs: %+v", bs)
+	}
+}
+
+func TestAddBreadcrumbs(t *testing.T) {
+	client := &Client{MaxBreadcrumbs: 4}
+	client.AddBreadcrumb(Breadcrumb{Message: "a"})
+	client.AddBreadcrumbs(Breadcrumb{Message: "b"}, Breadcrumb{Message: "c"})
+	client.AddBreadcrumbs(Breadcrumb{Message: "d"}, Breadcrumb{Message: "e"}, Breadcrumb{Message: "f"})
+	check := func(want string) {
+		t.Helper()
+		var got string
+		for _, b := range client.Breadcrumbs() {
+			if b.Timestamp == "" {
+				t.Errorf("breadcrumb %s has no timestamp", b.Message)
+			}
+			got += b.Message
+		}
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+	check("cdef")
+
+	// Only the end of a batch larger than the buffer is kept
+	client.AddBreadcrumbs(
+		Breadcrumb{Message: "g"}, Breadcrumb{Message: "h"}, Breadcrumb{Message: "i"},
+		Breadcrumb{Message: "j"}, Breadcrumb{Message: "k"},
+	)
+	check("hijk")
+}
+
+func TestAddBreadcrumbsConcurrent(t *testing.T) {
+	const goroutines, batches, batchSize = 10, 50, 5
+	client := &Client{MaxBreadcrumbs: goroutines * batches * (batchSize + 1)}
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				client.AddBreadcrumb(Breadcrumb{Category: "single"})
+				var bs []Breadcrumb
+				for j := 0; j < batchSize; j++ {
+					bs = append(bs, Breadcrumb{Category: fmt.Sprint(g, "-", i), Message: fmt.Sprint(j)})
+				}
+				client.AddBreadcrumbs(bs...)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	bs := client.Breadcrumbs()
+	if len(bs) != client.MaxBreadcrumbs {
+		t.Fatalf("got %d breadcrumbs, want %d", len(bs), client.MaxBreadcrumbs)
+	}
+	for i := 0; i < len(bs); i++ {
+		if bs[i].Category == "single" {
+			continue
+		}
+		// Each batch must be contiguous and in order
+		for j := 0; j < batchSize; j++ {
+			b := bs[i+j]
+			if b.Category != bs[i].Category || b.Message != fmt.Sprint(j) {
+				t.Fatalf("breadcrumb %d: got %s/%s, want %s/%d", i+j, b.Category, b.Message, bs[i].Category, j)
+			}
+		}
+		i += batchSize - 1
+	}
+}
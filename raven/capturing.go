@@ -0,0 +1,73 @@
+package raven
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CapturingTransport is an http.RoundTripper which records the events sent
+// through it instead of sending them, for testing code which reports to
+// Sentry. The events are decoded, so their fields can be inspected directly,
+// including the Attachments of events sent in envelopes.
+// Every event is accepted as a server would accept it. The zero value is ready
+// to use, eg:
+//
+//	tr := new(raven.CapturingTransport)
+//	client, err := raven.NewClient(dsn, raven.WithTransport(tr))
+//	...
+//	handle(client)
+//	if events := tr.Events(); len(events) != 1 || events[0].Level != raven.WARNING {
+//		t.Errorf("got %v, want a single warning", events)
+//	}
+//
+// Since no request leaves the process, the DSN needn't name a real server.
+type CapturingTransport struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+// Events returns the events captured so far, in the order they were sent.
+func (T *CapturingTransport) Events() []*Event {
+	T.mu.Lock()
+	defer T.mu.Unlock()
+	return append([]*Event(nil), T.events...)
+}
+
+// Reset discards the events captured so far.
+func (T *CapturingTransport) Reset() {
+	T.mu.Lock()
+	defer T.mu.Unlock()
+	T.events = nil
+}
+
+func (T *CapturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer req.Body.Close()
+	var ev *Event
+	var err error
+	if req.Header.Get("Content-Type") == envelopeContentType {
+		var attachments []Attachment
+		if ev, attachments, err = DecodeEnvelope(req.Body); err == nil {
+			ev.Attachments = attachments
+		}
+	} else {
+		ev, err = DecodeEvent(req.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	T.mu.Lock()
+	T.events = append(T.events, ev)
+	T.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(fmt.Sprintf(`{"id":%q}`, ev.EventId))),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,122 @@
+package raven
+
+import (
+	"context"
+	"sync"
+)
+
+// User is the Sentry interface describing the user affected by an event.
+type User struct {
+	Id        string `json:"id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Email     string `json:"email,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+type contextKey int
+
+const (
+	tagsKey contextKey = iota
+	userKey
+	transactionKey
+	hubKey
+	traceKey
+)
+
+// ContextWithTags returns a copy of ctx carrying the given tags, which are
+// added to events sent with CaptureContext. They are merged with any tags
+// already in ctx, replacing those with the same keys.
+func ContextWithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range tagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, tagsKey, merged)
+}
+
+// ContextWithUser returns a copy of ctx carrying user, which is set on events
+// sent with CaptureContext.
+func ContextWithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey, &user)
+}
+
+// transaction holds the name of a transaction, which may be set after the
+// context carrying it was created.
+type transaction struct {
+	mu   sync.Mutex
+	name string
+}
+
+// ContextWithTransaction returns a copy of ctx carrying the name of the
+// transaction, such as a route pattern, which is set on events sent with
+// CaptureContext. The name can be changed later with SetTransaction.
+func ContextWithTransaction(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, transactionKey, &transaction{name: name})
+}
+
+// SetTransaction changes the name of the transaction in ctx, which must have
+// been created by ContextWithTransaction. Requests handled by RecoveryHandler
+// have such a context, so routers can name the transaction once they have
+// matched the route. It reports whether ctx carries a transaction.
+func SetTransaction(ctx context.Context, name string) bool {
+	t, ok := ctx.Value(transactionKey).(*transaction)
+	if !ok {
+		return false
+	}
+	t.mu.Lock()
+	t.name = name
+	t.mu.Unlock()
+	return true
+}
+
+func transactionFromContext(ctx context.Context) string {
+	t, ok := ctx.Value(transactionKey).(*transaction)
+	if !ok {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.name
+}
+
+func tagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey).(map[string]string)
+	return tags
+}
+
+// applyContext adds the tags, user, transaction and trace stored in ctx to ev.
+// Values from ctx take precedence over those set on the event.
+func applyContext(ctx context.Context, ev *Event) {
+	if tags := tagsFromContext(ctx); len(tags) > 0 {
+		merged := make(map[string]string, len(ev.Tags)+len(tags))
+		for k, v := range ev.Tags {
+			merged[k] = v
+		}
+		for k, v := range tags {
+			merged[k] = v
+		}
+		ev.Tags = merged
+	}
+	if user, ok := ctx.Value(userKey).(*User); ok {
+		u := *user
+		ev.User = &u
+	}
+	if name := transactionFromContext(ctx); name != "" {
+		ev.Transaction = name
+	}
+	if t, ok := TraceFromContext(ctx); ok {
+		// The contexts may be shared with the caller
+		contexts := make(map[string]interface{}, len(ev.Contexts)+1)
+		for k, v := range ev.Contexts {
+			contexts[k] = v
+		}
+		ev.Contexts = contexts
+		ev.SetTrace(t)
+	}
+	if hub := HubFromContext(ctx); hub != nil {
+		hub.Scope().apply(ev)
+	}
+}
@@ -0,0 +1,58 @@
+package raven
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextWithTags(t *testing.T) {
+	ctx := ContextWithTags(context.Background(), map[string]string{"handler": "login", "region": "us"})
+	ctx = ContextWithTags(ctx, map[string]string{"region": "eu"})
+
+	tags := tagsFromContext(ctx)
+	if len(tags) != 2 || tags["handler"] != "login" || tags["region"] != "eu" {
+		t.Errorf("bad tags: %v", tags)
+	}
+}
+
+func TestCaptureContextValues(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	ctx := ContextWithTags(context.Background(), map[string]string{"handler": "login"})
+	ctx = ContextWithUser(ctx, User{Id: "42", Email: "user@example.com"})
+
+	eventTags := map[string]string{"handler": "signup", "attempt": "2"}
+	ev := &Event{Message: "Test message", Tags: eventTags, User: &User{Id: "7"}}
+	if err := client.CaptureContext(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	sent := <-events
+
+	// Context values take precedence over those set on the event
+	if sent.Tags["handler"] != "login" || sent.Tags["attempt"] != "2" {
+		t.Errorf("bad tags: %v", sent.Tags)
+	}
+	if sent.User == nil || sent.User.Id != "42" || sent.User.Email != "user@example.com" {
+		t.Errorf("bad user: %+v", sent.User)
+	}
+	if eventTags["handler"] != "signup" {
+		t.Error("the event's tags map was modified")
+	}
+}
+
+func TestSetTransaction(t *testing.T) {
+	if SetTransaction(context.Background(), "/users/:id") {
+		t.Error("a context without a transaction should not accept one")
+	}
+
+	ctx := ContextWithTransaction(context.Background(), "")
+	if !SetTransaction(ctx, "/users/:id") {
+		t.Fatal("the transaction was not set")
+	}
+	if name := transactionFromContext(ctx); name != "/users/:id" {
+		t.Errorf("bad transaction: got %q, want /users/:id", name)
+	}
+}
@@ -0,0 +1,51 @@
+package raven
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDebugClient(t *testing.T) {
+	var buf bytes.Buffer
+	client := NewDebugClient(&buf)
+
+	id, err := client.CaptureMessage("debug message")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatalf("the output is not an event: %v\n%s", err, buf.String())
+	}
+	if ev.EventId != id || ev.Message != "debug message" {
+		t.Errorf("bad event: %+v", ev)
+	}
+	// Defaults are filled in as for a real server
+	if ev.Level != ERROR || ev.Platform != "go" || ev.Timestamp == "" || len(ev.Stacktrace.Frames) == 0 {
+		t.Errorf("the event's defaults were not filled in: %+v", ev)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("\n  \"event_id\"")) {
+		t.Errorf("the event is not indented:\n%s", buf.String())
+	}
+}
+
+func TestDebugClientAttachments(t *testing.T) {
+	var buf bytes.Buffer
+	client := NewDebugClient(&buf)
+
+	attachment := Attachment{Filename: "state.json", ContentType: "application/json", Bytes: []byte(`{"queue":3}`)}
+	ev := &Event{Message: "with an attachment", Attachments: []Attachment{attachment}}
+	if err := client.Capture(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	var written Event
+	if err := json.Unmarshal(buf.Bytes(), &written); err != nil {
+		t.Fatalf("the output is not an event: %v\n%s", err, buf.String())
+	}
+	if written.EventId != ev.EventId || written.Message != "with an attachment" {
+		t.Errorf("bad event: %+v", written)
+	}
+}
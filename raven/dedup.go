@@ -0,0 +1,101 @@
+package raven
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// dedup tracks the events a client has sent recently so that repeats of them
+// can be suppressed. The zero value is ready to use.
+type dedup struct {
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+// dedupEntry records the repeats of an event during its window.
+type dedupEntry struct {
+	id    string // the id of the event which was sent
+	count int    // the number of times the event occurred, including the first
+	last  *Event // the latest repeat, if there has been one
+}
+
+// suppress reports whether ev, which has been prepared, repeats an event sent
+// within the client's DedupWindow. If it does the repeat is counted and ev is
+// given the id of the event which was sent. Otherwise a new window is opened
+// for ev, at the end of which any repeats are summarized in a single event.
+func (d *dedup) suppress(client *Client, ev *Event) bool {
+	key := dedupKey(ev)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.entries[key]; ok {
+		e.count++
+		e.last = ev.Clone()
+		ev.EventId = e.id
+		return true
+	}
+
+	if d.entries == nil {
+		d.entries = make(map[string]*dedupEntry)
+	}
+	d.entries[key] = &dedupEntry{id: ev.EventId, count: 1}
+	time.AfterFunc(client.DedupWindow, func() {
+		d.flush(client, key)
+	})
+	return false
+}
+
+// flush closes the window of the event with the given key, sending the latest
+// repeat of it with the number of times it was seen.
+func (d *dedup) flush(client *Client, key string) {
+	d.mu.Lock()
+	e := d.entries[key]
+	delete(d.entries, key)
+	d.mu.Unlock()
+	if e == nil || e.last == nil {
+		return
+	}
+
+	ev := e.last
+	id, err := client.newEventId()
+	if err != nil {
+		return
+	}
+	ev.EventId = id
+	if ev.Extra == nil {
+		ev.Extra = make(map[string]interface{})
+	}
+	ev.Extra["times_seen"] = e.count
+	client.encodeAndSend(context.Background(), ev)
+}
+
+// flushAll closes the windows of all events, sending the repeats seen so far.
+func (d *dedup) flushAll(client *Client) {
+	d.mu.Lock()
+	keys := make([]string, 0, len(d.entries))
+	for key := range d.entries {
+		keys = append(keys, key)
+	}
+	d.mu.Unlock()
+	for _, key := range keys {
+		d.flush(client, key)
+	}
+}
+
+// dedupKey identifies repeats of ev by its message and the frame where it
+// occurred.
+func dedupKey(ev *Event) string {
+	key := ev.Message
+	frames := ev.Stacktrace.Frames
+	if len(ev.Exceptions) > 0 {
+		if st := ev.Exceptions[len(ev.Exceptions)-1].Stacktrace; st != nil {
+			frames = st.Frames
+		}
+	}
+	if len(frames) > 0 {
+		f := frames[len(frames)-1]
+		key += "\x00" + f.FilePath + ":" + strconv.Itoa(f.LineNumber) + "\x00" + f.Function
+	}
+	return key
+}
@@ -0,0 +1,65 @@
+package raven
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	events := make(chan *Event, 10)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.DedupWindow = 100 * time.Millisecond
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := client.CaptureMessage("connection refused")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	// The same message from another place is a different event
+	if _, err := client.CaptureMessage("connection refused"); err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-events
+	if _, ok := first.Extra["times_seen"]; ok {
+		t.Errorf("the first event should not have a count: %v", first.Extra)
+	}
+	for i, id := range ids {
+		if id != first.EventId {
+			t.Errorf("capture %d: got id %s, want the id of the sent event %s", i, id, first.EventId)
+		}
+	}
+	if other := <-events; other.EventId == first.EventId {
+		t.Error("an event from another place should not be suppressed")
+	}
+
+	select {
+	case ev := <-events:
+		t.Fatalf("repeats should be suppressed until the window closes, got %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	select {
+	case summary := <-events:
+		// Numbers are decoded from JSON as float64
+		if n := summary.Extra["times_seen"]; n != float64(5) {
+			t.Errorf("bad times_seen: got %v, want 5", n)
+		}
+		if summary.EventId == first.EventId || summary.Message != "connection refused" {
+			t.Errorf("bad summary event: %+v", summary)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no summary event was sent when the window closed")
+	}
+
+	// A single occurrence in a window sends nothing when it closes
+	time.Sleep(150 * time.Millisecond)
+	if n := len(events); n != 0 {
+		t.Errorf("got %d unexpected events", n)
+	}
+}
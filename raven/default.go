@@ -0,0 +1,63 @@
+package raven
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNoDefaultClient is returned by the package-level capture functions when
+// SetDefault hasn't been called.
+var ErrNoDefaultClient = errors.New("raven: no default client has been set")
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient *Client
+)
+
+// SetDefault sets the client used by the package-level capture functions,
+// such as CaptureError, so that code can report events without being given a
+// client. A nil client unsets the default.
+func SetDefault(client *Client) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = client
+}
+
+// Default returns the client set by SetDefault, or nil if there is none.
+func Default() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
+
+// CaptureError sends err to Sentry with the default client, as
+// Client.CaptureError does. It returns ErrNoDefaultClient if there is none.
+func CaptureError(err error) (string, error) {
+	client := Default()
+	if client == nil {
+		return "", ErrNoDefaultClient
+	}
+	return client.CaptureError(err)
+}
+
+// CaptureMessagef sends a formatted message to Sentry with the default client,
+// as Client.CaptureMessagef does. It returns ErrNoDefaultClient if there is
+// none.
+func CaptureMessagef(format string, args ...interface{}) (string, error) {
+	client := Default()
+	if client == nil {
+		return "", ErrNoDefaultClient
+	}
+	return client.CaptureMessagef(format, args...)
+}
+
+// CaptureMessageWithLevel sends a message of the given level to Sentry with
+// the default client, as Client.CaptureMessageWithLevel does. It returns
+// ErrNoDefaultClient if there is none.
+func CaptureMessageWithLevel(level Severity, message string) (string, error) {
+	client := Default()
+	if client == nil {
+		return "", ErrNoDefaultClient
+	}
+	return client.CaptureMessageWithLevel(level, message)
+}
@@ -0,0 +1,57 @@
+package raven
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefaultClient(t *testing.T) {
+	defer SetDefault(nil)
+	SetDefault(nil)
+	if _, err := CaptureError(errors.New("no client")); err != ErrNoDefaultClient {
+		t.Errorf("CaptureError: got %v, want ErrNoDefaultClient", err)
+	}
+	if _, err := CaptureMessagef("no %s", "client"); err != ErrNoDefaultClient {
+		t.Errorf("CaptureMessagef: got %v, want ErrNoDefaultClient", err)
+	}
+	if _, err := CaptureMessageWithLevel(INFO, "no client"); err != ErrNoDefaultClient {
+		t.Errorf("CaptureMessageWithLevel: got %v, want ErrNoDefaultClient", err)
+	}
+
+	events := make(chan *Event, 3)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	SetDefault(client)
+	if Default() != client {
+		t.Fatal("Default did not return the client given to SetDefault")
+	}
+
+	if id, err := CaptureError(errors.New("with client")); err != nil || id == "" {
+		t.Fatalf("CaptureError: got %q, %v", id, err)
+	}
+	ev := <-events
+	if ev.Message != "with client" || len(ev.Exceptions) != 1 {
+		t.Errorf("CaptureError sent %+v", ev)
+	}
+	for _, f := range ev.Exceptions[0].Stacktrace.Frames {
+		if strings.Contains(f.Function, "Capture") {
+			t.Errorf("the stacktrace includes %s", f.Function)
+		}
+	}
+
+	if _, err := CaptureMessagef("with %s", "client"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Message != "with client" || ev.Level != ERROR {
+		t.Errorf("CaptureMessagef sent %s: %s", ev.Level, ev.Message)
+	}
+
+	if _, err := CaptureMessageWithLevel(WARNING, "with client"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Message != "with client" || ev.Level != WARNING {
+		t.Errorf("CaptureMessageWithLevel sent %s: %s", ev.Level, ev.Message)
+	}
+}
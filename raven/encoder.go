@@ -0,0 +1,214 @@
+package raven
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// An Encoder serializes events into the form sent to the server: JSON which
+// is compressed with zlib and then base64 encoded. A raw Encoder, returned by
+// NewRawEncoder, leaves out the compression, and a gzip Encoder, returned by
+// NewGzipEncoder, sends gzipped JSON with a Content-Encoding header instead.
+type Encoder struct {
+	level int
+	raw   bool
+	gzip  bool
+
+	// pool holds *encoderStates to be reused, so that encoding allocates
+	// little besides its result.
+	pool sync.Pool
+}
+
+// encoderState is the buffer and compressor used by a single call to Encode.
+type encoderState struct {
+	buf bytes.Buffer
+	zw  compressor
+}
+
+// compressor is implemented by both *zlib.Writer and *gzip.Writer.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// maxPooledBuffer is the capacity above which a buffer is not kept for reuse,
+// so an unusually large event doesn't hold on to its memory.
+const maxPooledBuffer = 1 << 20
+
+// defaultEncoder is used by clients which don't set an Encoder.
+var defaultEncoder = &Encoder{level: zlib.DefaultCompression}
+
+// NewEncoder returns an Encoder which compresses at the given zlib level.
+// Lower levels are faster and higher ones produce smaller payloads. The level
+// must be zlib.DefaultCompression, zlib.HuffmanOnly, or between
+// zlib.NoCompression and zlib.BestCompression inclusive.
+func NewEncoder(level int) (*Encoder, error) {
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		return nil, fmt.Errorf("invalid compression level: %d", level)
+	}
+	return &Encoder{level: level}, nil
+}
+
+// NewRawEncoder returns an Encoder which base64 encodes the JSON of events
+// without compressing it, so that what is sent can be read with nothing more
+// than a base64 decoder, eg: when troubleshooting a self-hosted server. The
+// payloads are several times larger than compressed ones.
+func NewRawEncoder() *Encoder {
+	return &Encoder{level: zlib.NoCompression, raw: true}
+}
+
+// NewGzipEncoder returns an Encoder which compresses the JSON of events with
+// gzip at the given level and sends it as is, with "Content-Encoding: gzip".
+// Unlike the zlib and base64 payloads of other Encoders this is understood by
+// most HTTP servers and proxies, which some Sentry-compatible servers need.
+// The level is validated as it is by NewEncoder.
+//
+// Events with attachments are sent in an envelope, which is never compressed.
+func NewGzipEncoder(level int) (*Encoder, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid compression level: %d", level)
+	}
+	return &Encoder{level: level, gzip: true}, nil
+}
+
+// contentType returns the content type of the payloads made by the Encoder.
+func (e *Encoder) contentType() string {
+	switch {
+	case e.raw:
+		return "text/plain"
+	case e.gzip:
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
+
+// contentEncoding returns the Content-Encoding of the payloads made by the
+// Encoder, or "" if they have none.
+func (e *Encoder) contentEncoding() string {
+	if e.gzip {
+		return "gzip"
+	}
+	return ""
+}
+
+// Level returns the compression level of the Encoder, which is
+// zlib.NoCompression for a raw Encoder.
+func (e *Encoder) Level() int {
+	return e.level
+}
+
+// Encode serializes the given event. If the event can't be marshalled, eg:
+// because its Extra holds a channel, the error from encoding/json is returned
+// wrapped. Encode may be called from several goroutines at once.
+func (e *Encoder) Encode(ev *Event) ([]byte, error) {
+	if e.raw {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return nil, fmt.Errorf("encoding the event: %w", err)
+		}
+		buf := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+		base64.StdEncoding.Encode(buf, data)
+		return buf, nil
+	}
+
+	st, err := e.getState()
+	if err != nil {
+		return nil, err
+	}
+	// The state is reset before it is used again, so it is returned to the
+	// pool whether or not encoding succeeds.
+	defer e.putState(st)
+
+	if err := json.NewEncoder(st.zw).Encode(ev); err != nil {
+		return nil, fmt.Errorf("encoding the event: %w", err)
+	}
+	if err := st.zw.Close(); err != nil {
+		return nil, err
+	}
+	if e.gzip {
+		return append([]byte(nil), st.buf.Bytes()...), nil
+	}
+	buf := make([]byte, base64.StdEncoding.EncodedLen(st.buf.Len()))
+	base64.StdEncoding.Encode(buf, st.buf.Bytes())
+	return buf, nil
+}
+
+// getState returns an encoderState from the pool, or a new one, ready for use.
+func (e *Encoder) getState() (*encoderState, error) {
+	if st, ok := e.pool.Get().(*encoderState); ok {
+		st.buf.Reset()
+		st.zw.Reset(&st.buf)
+		return st, nil
+	}
+	st := new(encoderState)
+	var err error
+	if e.gzip {
+		st.zw, err = gzip.NewWriterLevel(&st.buf, e.level)
+	} else {
+		st.zw, err = zlib.NewWriterLevel(&st.buf, e.level)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (e *Encoder) putState(st *encoderState) {
+	if st.buf.Cap() <= maxPooledBuffer {
+		e.pool.Put(st)
+	}
+}
+
+// DecodeEvent reads an event serialized by any Encoder, telling gzip from
+// base64 payloads by their first bytes. It is the inverse of Encode, and is
+// useful in tests for decoding the events received by a mock Sentry server.
+func DecodeEvent(r io.Reader) (*Event, error) {
+	gr := bufio.NewReader(r)
+	if magic, err := gr.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		reader, err := gzip.NewReader(gr)
+		if err != nil {
+			return nil, err
+		}
+		ev := new(Event)
+		if err := json.NewDecoder(reader).Decode(ev); err != nil {
+			return nil, err
+		}
+		if err := reader.Close(); err != nil {
+			return nil, err
+		}
+		return ev, nil
+	}
+
+	br := bufio.NewReader(base64.NewDecoder(base64.StdEncoding, gr))
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	ev := new(Event)
+	if first[0] == '{' {
+		// The JSON of a raw Encoder
+		if err := json.NewDecoder(br).Decode(ev); err != nil {
+			return nil, err
+		}
+		return ev, nil
+	}
+
+	reader, err := zlib.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(reader).Decode(ev); err != nil {
+		return nil, err
+	}
+	if err := reader.Close(); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
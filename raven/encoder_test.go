@@ -0,0 +1,298 @@
+package raven
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewEncoder(t *testing.T) {
+	for _, level := range []int{zlib.HuffmanOnly, zlib.DefaultCompression, zlib.NoCompression, zlib.BestSpeed, zlib.BestCompression} {
+		e, err := NewEncoder(level)
+		if err != nil {
+			t.Errorf("level %d: %s", level, err)
+			continue
+		}
+		buf, err := e.Encode(&Event{Message: "test message"})
+		if err != nil {
+			t.Errorf("level %d: %s", level, err)
+			continue
+		}
+		ev, err := DecodeEvent(bytes.NewReader(buf))
+		if err != nil {
+			t.Errorf("level %d: %s", level, err)
+			continue
+		}
+		if ev.Message != "test message" {
+			t.Errorf("level %d: bad message: %s", level, ev.Message)
+		}
+	}
+
+	for _, level := range []int{-3, 10} {
+		if _, err := NewEncoder(level); err == nil {
+			t.Errorf("level %d: expected an error", level)
+		}
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	ev := &Event{EventId: "abc123", Message: "test message", Tags: map[string]string{"a": "b"}}
+	buf, err := defaultEncoder.Encode(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeEvent(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.EventId != ev.EventId || decoded.Message != ev.Message || decoded.Tags["a"] != "b" {
+		t.Errorf("bad event: %+v", decoded)
+	}
+
+	for _, s := range []string{"", "not base64!", "aGVsbG8="} {
+		if _, err := DecodeEvent(strings.NewReader(s)); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	ev := &Event{Message: "test message", Extra: map[string]interface{}{"ch": make(chan int)}}
+	buf, err := defaultEncoder.Encode(ev)
+	var jerr *json.UnsupportedTypeError
+	if !errors.As(err, &jerr) {
+		t.Fatalf("got %v, want a json.UnsupportedTypeError", err)
+	}
+	if buf != nil {
+		t.Errorf("got %d bytes, want none", len(buf))
+	}
+
+	// The encoder is still usable after a failure
+	if _, err := defaultEncoder.Encode(&Event{Message: "test message"}); err != nil {
+		t.Error(err)
+	}
+
+	// Failed encodes don't leave anything behind
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		defaultEncoder.Encode(ev)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines grew from %d to %d", before, after)
+	}
+}
+
+func TestEncodeConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				msg := fmt.Sprint("goroutine ", i, " event ", j, strings.Repeat(".", j*100))
+				buf, err := defaultEncoder.Encode(&Event{Message: msg})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ev, err := DecodeEvent(bytes.NewReader(buf))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if ev.Message != msg {
+					t.Errorf("got %.40q, want %.40q", ev.Message, msg)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRawEncoder(t *testing.T) {
+	e := NewRawEncoder()
+	buf, err := e.Encode(&Event{EventId: "abc123", Message: "test message"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The payload is plain base64 JSON, without a zlib step
+	data, err := base64.StdEncoding.DecodeString(string(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		t.Fatalf("the payload is not JSON: %v\n%s", err, data)
+	}
+	if ev.EventId != "abc123" || ev.Message != "test message" {
+		t.Errorf("bad event: %+v", ev)
+	}
+
+	decoded, err := DecodeEvent(bytes.NewReader(buf))
+	if err != nil || decoded.Message != "test message" {
+		t.Errorf("DecodeEvent: got %+v, %v", decoded, err)
+	}
+
+	if _, err := e.Encode(&Event{Extra: map[string]interface{}{"ch": make(chan int)}}); err == nil {
+		t.Error("expected an error for an event which can't be marshalled")
+	}
+}
+
+func TestClientRawEncoder(t *testing.T) {
+	contentTypes := make(chan string, 1)
+	events := make(chan *Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			contentTypes <- req.Header.Get("Content-Type")
+			ev, err := DecodeEvent(req.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events <- ev
+			fmt.Fprint(w, "hello")
+		}))
+	defer server.Close()
+	client := GetClient(server)
+	client.Encoder = NewRawEncoder()
+
+	if _, err := client.CaptureMessage("raw"); err != nil {
+		t.Fatal(err)
+	}
+	if ct := <-contentTypes; ct != "text/plain" {
+		t.Errorf("got content type %q, want text/plain", ct)
+	}
+	if ev := <-events; ev.Message != "raw" {
+		t.Errorf("bad message: %s", ev.Message)
+	}
+}
+
+func TestClientEncoder(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	var err error
+	if client.Encoder, err = NewEncoder(zlib.BestSpeed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CaptureMessage("fast"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Message != "fast" {
+		t.Errorf("bad message: %s", ev.Message)
+	}
+}
+
+func TestClientGzipEncoder(t *testing.T) {
+	type request struct {
+		contentType, contentEncoding string
+		ev                           *Event
+		err                          error
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			// Decode the body as any HTTP server would, without DecodeEvent
+			r := request{contentType: req.Header.Get("Content-Type"), contentEncoding: req.Header.Get("Content-Encoding")}
+			zr, err := gzip.NewReader(req.Body)
+			if err == nil {
+				r.ev = new(Event)
+				err = json.NewDecoder(zr).Decode(r.ev)
+			}
+			r.err = err
+			requests <- r
+			fmt.Fprint(w, "hello")
+		}))
+	defer server.Close()
+	client := GetClient(server)
+
+	var err error
+	if client.Encoder, err = NewGzipEncoder(gzip.DefaultCompression); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CaptureMessage("gzipped"); err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+	if r.contentType != "application/json" || r.contentEncoding != "gzip" {
+		t.Errorf("got content type %q and encoding %q, want application/json and gzip", r.contentType, r.contentEncoding)
+	}
+	if r.err != nil {
+		t.Fatalf("the payload is not gzipped JSON: %v", r.err)
+	}
+	if r.ev.Message != "gzipped" {
+		t.Errorf("bad message: %s", r.ev.Message)
+	}
+
+	// DecodeEvent tells gzip payloads from base64 ones
+	buf, err := client.Encoder.Encode(&Event{Message: "gzipped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, err := DecodeEvent(bytes.NewReader(buf)); err != nil || ev.Message != "gzipped" {
+		t.Errorf("DecodeEvent: got %+v, %v", ev, err)
+	}
+
+	for _, level := range []int{-3, 10} {
+		if _, err := NewGzipEncoder(level); err == nil {
+			t.Errorf("level %d: expected an error", level)
+		}
+	}
+}
+
+// BenchmarkEncode compares the time taken to encode an event, and the size of
+// the result, at different compression levels.
+func BenchmarkEncode(b *testing.B) {
+	ev := &Event{
+		EventId:   "0123456789abcdef0123456789abcdef",
+		Project:   "1",
+		Message:   strings.Repeat("something went wrong ", 20),
+		Timestamp: "2013-10-17T11:25:59",
+		Level:     "error",
+		Logger:    "root",
+		Platform:  "go",
+		Extra:     map[string]interface{}{"request": strings.Repeat("x", 4096)},
+	}
+	ev.Stacktrace = generateStacktrace(0, nil)
+
+	levels := []struct {
+		name  string
+		level int
+	}{
+		{"BestSpeed", zlib.BestSpeed},
+		{"Default", zlib.DefaultCompression},
+		{"BestCompression", zlib.BestCompression},
+	}
+	for _, l := range levels {
+		e, err := NewEncoder(l.level)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(l.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var size int
+			for i := 0; i < b.N; i++ {
+				buf, err := e.Encode(ev)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(buf)
+			}
+			b.ReportMetric(float64(size), "bytes")
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package raven
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// maxErrorChain limits how many wrapped errors are unwrapped into exceptions,
+// in case an error's Unwrap method forms a cycle.
+const maxErrorChain = 10
+
+// Exception is the Sentry interface describing an error.
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Module     string      `json:"module,omitempty"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+	Mechanism  *Mechanism  `json:"mechanism,omitempty"`
+}
+
+// Mechanism describes how an exception was captured. Handled is false for
+// exceptions which escaped the program's error handling, such as panics, which
+// Sentry counts as crashes, and true for errors reported explicitly. It is nil
+// if that isn't known.
+type Mechanism struct {
+	Type    string `json:"type"`
+	Handled *bool  `json:"handled,omitempty"`
+}
+
+// newMechanism returns a mechanism of the given type with Handled set.
+func newMechanism(mechanismType string, handled bool) *Mechanism {
+	return &Mechanism{Type: mechanismType, Handled: &handled}
+}
+
+// setMechanism sets the mechanism of the outermost of exceptions, which
+// describes the event.
+func setMechanism(exceptions []Exception, m *Mechanism) {
+	if len(exceptions) > 0 {
+		exceptions[len(exceptions)-1].Mechanism = m
+	}
+}
+
+// exceptionValues is the wire format of the exception interface.
+type exceptionValues struct {
+	Values []Exception `json:"values"`
+}
+
+// NewException creates the exception interface for err, which occurred at the
+// given stacktrace. The type of the exception is the name of err's type. If
+// err is nil the exception has only the stacktrace.
+func NewException(err error, stacktrace *Stacktrace) Exception {
+	if err == nil {
+		return Exception{Stacktrace: stacktrace}
+	}
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	typeName := t.Name()
+	if typeName == "" {
+		typeName = t.String()
+	}
+	return Exception{Type: typeName, Value: err.Error(), Module: t.PkgPath(), Stacktrace: stacktrace}
+}
+
+// LevelError is implemented by errors which report the level they should be
+// sent to Sentry at, eg: WARNING for errors which are expected now and then.
+type LevelError interface {
+	error
+	Level() Severity
+}
+
+// NewExceptions creates an exception for err and for every error it wraps, as
+// returned by errors.Unwrap. The innermost cause is first, as Sentry expects,
+// and err itself is last and carries the stacktrace.
+func NewExceptions(err error, stacktrace *Stacktrace) []Exception {
+	var chain []error
+	for e := err; e != nil && len(chain) < maxErrorChain; e = errors.Unwrap(e) {
+		chain = append(chain, e)
+	}
+	exceptions := make([]Exception, len(chain))
+	for i, e := range chain {
+		exceptions[len(chain)-1-i] = NewException(e, nil)
+	}
+	if len(exceptions) > 0 {
+		exceptions[len(exceptions)-1].Stacktrace = stacktrace
+	}
+	return exceptions
+}
+
+// CaptureException sends err to Sentry with the stacktrace of the caller and
+// the given tags, which may be nil. Errors wrapped by err are sent as the
+// chain of exceptions which caused it, marked as handled, and the event is
+// tagged with the type of err as error.type unless the client's
+// DisableErrorTypeTag is set. The stacktrace is that of the first
+// StackProvider in the chain, if there is one. The event is sent at the level
+// of the first error in the chain which is a LevelError, or else at ERROR. It
+// returns the Sentry event ID or an empty string and any error that occurred.
+//
+// If err is nil a message saying so is sent instead.
+func (client *Client) CaptureException(err error, tags map[string]string) (string, error) {
+	ev := client.newExceptionEvent(err, tags)
+	if err := client.Capture(ev); err != nil {
+		return "", err
+	}
+	return ev.EventId, nil
+}
+
+// newExceptionEvent creates the event sent by CaptureException.
+func (client *Client) newExceptionEvent(err error, tags map[string]string) *Event {
+	ev := &Event{Tags: cloneStrings(tags)}
+	if err == nil {
+		ev.Message = "CaptureException was called with a nil error"
+		return ev
+	}
+
+	var stacktrace Stacktrace
+	var provider StackProvider
+	if errors.As(err, &provider) {
+		stacktrace.Frames = provider.StackFrames()
+	}
+	if len(stacktrace.Frames) == 0 {
+		stacktrace = generateStacktrace(0, client.ExcludePaths)
+	}
+	ev.Message = err.Error()
+	ev.Exceptions = NewExceptions(err, &stacktrace)
+	setMechanism(ev.Exceptions, newMechanism("generic", true))
+	var levelErr LevelError
+	if errors.As(err, &levelErr) {
+		ev.Level = levelErr.Level()
+	}
+	if _, ok := ev.Tags["error.type"]; !ok && !client.DisableErrorTypeTag {
+		if ev.Tags == nil {
+			ev.Tags = make(map[string]string)
+		}
+		ev.Tags["error.type"] = reflect.TypeOf(err).String()
+	}
+	for name, sentinel := range client.SentinelErrors {
+		if !errors.Is(err, sentinel) {
+			continue
+		}
+		if ev.Tags == nil {
+			ev.Tags = make(map[string]string)
+		}
+		ev.Tags["matches."+name] = "true"
+	}
+	return ev
+}
+
+// CaptureError sends err and the chain of errors it wraps to Sentry.
+// It is a shorthand for CaptureException without tags.
+func (client *Client) CaptureError(err error) (string, error) {
+	return client.CaptureException(err, nil)
+}
+
+// CaptureErrorAndWait sends err to Sentry as CaptureError does, and doesn't
+// return until the server has responded. It is the call to make in fatal
+// handlers, right before os.Exit or log.Fatal, when the error must reach Sentry
+// before the process ends.
+//
+// The event follows the client's LevelRouting, but it isn't subject to
+// SampleRate, MaxEventsPerSecond or DedupWindow, and is never added to the
+// spool: if it can't be sent, the error is returned so that it can at least be
+// logged before exiting.
+func (client *Client) CaptureErrorAndWait(err error) (string, error) {
+	ev := client.newExceptionEvent(err, nil)
+	client = client.route(ev)
+	copyMaps(ev)
+	if err := client.prepare(ev); err != nil {
+		return "", err
+	}
+	buf, err := client.encode(ev)
+	if err != nil {
+		return "", err
+	}
+	timestamp, err := ev.parseTimestamp()
+	if err != nil {
+		return "", err
+	}
+	if err := client.send(context.Background(), buf, timestamp); err != nil {
+		client.sendFailed(ev, err)
+		return "", err
+	}
+	return ev.EventId, nil
+}
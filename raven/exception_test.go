@@ -0,0 +1,373 @@
+package raven
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type queryError struct {
+	query string
+	err   error
+}
+
+func (e *queryError) Error() string { return e.query + ": " + e.err.Error() }
+func (e *queryError) Unwrap() error { return e.err }
+
+// cyclicError unwraps to itself.
+type cyclicError struct{}
+
+func (e *cyclicError) Error() string { return "cycle" }
+func (e *cyclicError) Unwrap() error { return e }
+
+func TestNewException(t *testing.T) {
+	tests := []struct {
+		err         error
+		typ, module string
+	}{
+		{errors.New("oops"), "errorString", "errors"},
+		{&queryError{"SELECT 1", errors.New("oops")}, "queryError", "github.com/kisielk/raven-go/raven"},
+	}
+	for _, test := range tests {
+		e := NewException(test.err, nil)
+		if e.Type != test.typ || e.Module != test.module || e.Value != test.err.Error() {
+			t.Errorf("%v: got %+v, want type %s, module %s", test.err, e, test.typ, test.module)
+		}
+	}
+
+	stacktrace := &Stacktrace{Frames: []Frame{{Function: "main.main"}}}
+	if e := NewException(nil, stacktrace); !reflect.DeepEqual(e, Exception{Stacktrace: stacktrace}) {
+		t.Errorf("nil error: got %+v, want only the stacktrace", e)
+	}
+}
+
+func TestCaptureException(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	tags := map[string]string{"component": "db"}
+	id, err := client.CaptureException(errors.New("connection refused"), tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if ev.EventId != id {
+		t.Errorf("bad event id: got %s, want %s", ev.EventId, id)
+	}
+	if ev.Message != "connection refused" || ev.Tags["component"] != "db" {
+		t.Errorf("bad event: %+v", ev)
+	}
+	if len(ev.Exceptions) != 1 {
+		t.Fatalf("got %d exceptions, want 1", len(ev.Exceptions))
+	}
+	e := ev.Exceptions[0]
+	if e.Type != "errorString" || e.Value != "connection refused" {
+		t.Errorf("bad exception: %+v", e)
+	}
+	if e.Stacktrace == nil || len(e.Stacktrace.Frames) == 0 {
+		t.Fatal("the exception has no stacktrace")
+	}
+	if e.Stacktrace.Frames[0].Function != "TestCaptureException" {
+		t.Errorf("bad first frame: %+v", e.Stacktrace.Frames[0])
+	}
+	if len(ev.Stacktrace.Frames) != 0 {
+		t.Error("the event should not have a separate stacktrace")
+	}
+}
+
+func TestCaptureExceptionNil(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	if _, err := client.CaptureException(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if !strings.Contains(ev.Message, "nil error") {
+		t.Errorf("bad message: %s", ev.Message)
+	}
+	if len(ev.Exceptions) != 0 {
+		t.Errorf("got %d exceptions, want 0", len(ev.Exceptions))
+	}
+}
+
+func TestNewExceptions(t *testing.T) {
+	root := errors.New("connection refused")
+	err := fmt.Errorf("loading user: %w", &queryError{"SELECT 1", root})
+	stacktrace := &Stacktrace{Frames: []Frame{{Function: "main.main"}}}
+
+	exceptions := NewExceptions(err, stacktrace)
+	want := []struct{ typ, value string }{
+		{"errorString", "connection refused"},
+		{"queryError", "SELECT 1: connection refused"},
+		{"wrapError", "loading user: SELECT 1: connection refused"},
+	}
+	if len(exceptions) != len(want) {
+		t.Fatalf("got %d exceptions, want %d: %+v", len(exceptions), len(want), exceptions)
+	}
+	for i, e := range exceptions {
+		if e.Type != want[i].typ || e.Value != want[i].value {
+			t.Errorf("exception %d: got %s %q, want %s %q", i, e.Type, e.Value, want[i].typ, want[i].value)
+		}
+	}
+	if exceptions[2].Stacktrace != stacktrace || exceptions[0].Stacktrace != nil || exceptions[1].Stacktrace != nil {
+		t.Error("only the outermost exception should have the stacktrace")
+	}
+
+	if n := len(NewExceptions(&cyclicError{}, nil)); n != maxErrorChain {
+		t.Errorf("got %d exceptions for a cyclic error, want %d", n, maxErrorChain)
+	}
+}
+
+func TestCaptureError(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	err := fmt.Errorf("loading user: %w", errors.New("connection refused"))
+	if _, err := client.CaptureError(err); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if len(ev.Exceptions) != 2 {
+		t.Fatalf("got %d exceptions, want 2", len(ev.Exceptions))
+	}
+	if ev.Exceptions[0].Value != "connection refused" || ev.Exceptions[1].Value != err.Error() {
+		t.Errorf("bad exceptions: %+v", ev.Exceptions)
+	}
+}
+
+// cacheMissError is an expected error, reported as a warning.
+type cacheMissError struct{ key string }
+
+func (e cacheMissError) Error() string   { return "cache miss: " + e.key }
+func (e cacheMissError) Level() Severity { return WARNING }
+
+func TestCaptureErrorLevel(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	tests := []struct {
+		err   error
+		level Severity
+	}{
+		{cacheMissError{"user:1"}, WARNING},
+		{fmt.Errorf("loading user: %w", cacheMissError{"user:1"}), WARNING},
+		{errors.New("connection refused"), ERROR},
+	}
+	for _, test := range tests {
+		if _, err := client.CaptureError(test.err); err != nil {
+			t.Fatal(err)
+		}
+		if ev := <-events; ev.Level != test.level {
+			t.Errorf("%v: got level %s, want %s", test.err, ev.Level, test.level)
+		}
+	}
+}
+
+// stackError carries the program counters of where it was created, as the
+// errors of many libraries do.
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 32)
+	return &stackError{msg, pcs[:runtime.Callers(1, pcs)]}
+}
+
+func (e *stackError) Error() string        { return e.msg }
+func (e *stackError) StackFrames() []Frame { return FramesFromPCs(e.pcs) }
+
+func TestCaptureErrorStackProvider(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	var err error
+	stackAt(3, func() { err = newStackError("created elsewhere") })
+	if _, err := client.CaptureError(fmt.Errorf("wrapped: %w", err)); err != nil {
+		t.Fatal(err)
+	}
+	exceptions := (<-events).Exceptions
+	frames := exceptions[len(exceptions)-1].Stacktrace.Frames
+	if fn := frames[len(frames)-1].Function; fn != "newStackError" {
+		t.Errorf("the stacktrace should end where the error was created, not in %s", fn)
+	}
+	if fn := frames[len(frames)-3].Function; fn != "stackAt" {
+		t.Errorf("the error was created from stackAt, not %s", fn)
+	}
+
+	// Errors without a stack get the stacktrace of the caller
+	if _, err := client.CaptureError(&stackError{msg: "no stack"}); err != nil {
+		t.Fatal(err)
+	}
+	exceptions = (<-events).Exceptions
+	frames = exceptions[len(exceptions)-1].Stacktrace.Frames
+	if fn := frames[len(frames)-1].Function; fn != "TestCaptureErrorStackProvider" {
+		t.Errorf("the stacktrace should end in the test, not in %s", fn)
+	}
+}
+
+// framesError returns the same frames every time it is asked for them.
+type framesError struct {
+	frames []Frame
+}
+
+func (e *framesError) Error() string        { return "stored frames" }
+func (e *framesError) StackFrames() []Frame { return e.frames }
+
+func TestCaptureErrorSharedFrames(t *testing.T) {
+	const n = 8
+	events := make(chan *Event, n)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.InAppPrefixes = []string{"github.com/example/lib"}
+
+	err := &framesError{}
+	for i := 0; i < 5; i++ {
+		err.frames = append(err.frames, newFrame("github.com/example/app.f", "/src/app/f.go", i+1))
+	}
+	want := append([]Frame(nil), err.frames...)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.CaptureError(err); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		exceptions := (<-events).Exceptions
+		if frames := exceptions[len(exceptions)-1].Stacktrace.Frames; len(frames) != 5 || frames[0].InApp {
+			t.Errorf("bad frames: %+v", frames)
+		}
+	}
+	if !reflect.DeepEqual(err.frames, want) {
+		t.Errorf("the provider's frames were modified: got %+v, want %+v", err.frames, want)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	errNotFound := errors.New("not found")
+	errTimeout := errors.New("timeout")
+	client.SentinelErrors = map[string]error{"ErrNotFound": errNotFound, "ErrTimeout": errTimeout}
+	client.DisableErrorTypeTag = true
+
+	err := &queryError{"SELECT * FROM users", fmt.Errorf("no user 42: %w", errNotFound)}
+	if _, err := client.CaptureError(err); err != nil {
+		t.Fatal(err)
+	}
+	if tags, want := (<-events).Tags, map[string]string{"matches.ErrNotFound": "true"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %v, want %v", tags, want)
+	}
+
+	if _, err := client.CaptureError(errors.New("unrelated")); err != nil {
+		t.Fatal(err)
+	}
+	if tags := (<-events).Tags; len(tags) != 0 {
+		t.Errorf("got tags %v for an error matching no sentinels", tags)
+	}
+}
+
+func TestErrorTypeTag(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	err := &queryError{"SELECT 1", errors.New("oops")}
+	if _, err := client.CaptureError(err); err != nil {
+		t.Fatal(err)
+	}
+	if tag := (<-events).Tags["error.type"]; tag != "*raven.queryError" {
+		t.Errorf("bad error.type tag: got %q, want *raven.queryError", tag)
+	}
+
+	// A tag given by the caller is kept
+	if _, err := client.CaptureException(err, map[string]string{"error.type": "query"}); err != nil {
+		t.Fatal(err)
+	}
+	if tag := (<-events).Tags["error.type"]; tag != "query" {
+		t.Errorf("bad error.type tag: got %q, want query", tag)
+	}
+
+	client.DisableErrorTypeTag = true
+	if _, err := client.CaptureError(err); err != nil {
+		t.Fatal(err)
+	}
+	if tag, ok := (<-events).Tags["error.type"]; ok {
+		t.Errorf("the error.type tag should not be set when disabled: got %q", tag)
+	}
+}
+
+func TestCaptureErrorAndWait(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+			if _, err := DecodeEvent(req.Body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			atomic.StoreInt32(&received, 1)
+			fmt.Fprint(w, "hello")
+		}))
+	defer server.Close()
+	client := GetClient(server)
+	client.SampleRate = 0
+	client.DedupWindow = time.Minute
+
+	id, err := client.CaptureErrorAndWait(errors.New("fatal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" {
+		t.Error("got no event id")
+	}
+	if atomic.LoadInt32(&received) != 1 {
+		t.Error("returned before the server received the event")
+	}
+}
+
+func TestCaptureErrorAndWaitFailure(t *testing.T) {
+	server := newFailingServer()
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := client.CaptureErrorAndWait(errors.New("fatal")); err == nil {
+		t.Error("expected an error")
+	}
+	if queued, _ := client.SpoolStats(); queued != 0 {
+		t.Errorf("%d events were spooled, want none", queued)
+	}
+}
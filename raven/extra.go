@@ -0,0 +1,138 @@
+package raven
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// addExtra adds the given values to the extra data of ev, without replacing
+// any values it already has. The extra data is modified in place, so it must
+// not be the caller's, which copyMaps copies.
+func addExtra(ev *Event, extra map[string]interface{}) {
+	if ev.Extra == nil {
+		ev.Extra = make(map[string]interface{}, len(extra))
+	}
+	for k, v := range extra {
+		if _, ok := ev.Extra[k]; !ok {
+			ev.Extra[k] = v
+		}
+	}
+}
+
+// runtimeExtra returns the number of goroutines and a summary of the memory
+// statistics. Reading the statistics briefly stops the world but does not
+// trigger a garbage collection.
+func runtimeExtra() map[string]interface{} {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return map[string]interface{}{
+		"runtime.goroutines":     runtime.NumGoroutine(),
+		"runtime.heap_alloc":     m.HeapAlloc,
+		"runtime.heap_objects":   m.HeapObjects,
+		"runtime.num_gc":         m.NumGC,
+		"runtime.gc_pause_total": m.PauseTotalNs,
+	}
+}
+
+// processExtra returns the working directory, if it can be found, and the
+// command line arguments of the program.
+func processExtra() map[string]interface{} {
+	extra := map[string]interface{}{"process.args": append([]string(nil), os.Args...)}
+	if wd, err := os.Getwd(); err == nil {
+		extra["process.cwd"] = wd
+	}
+	return extra
+}
+
+// addContexts adds the given contexts to ev, without replacing any contexts
+// it already has.
+func addContexts(ev *Event, contexts map[string]interface{}) {
+	if ev.Contexts == nil {
+		ev.Contexts = make(map[string]interface{}, len(contexts))
+	}
+	for k, v := range contexts {
+		if _, ok := ev.Contexts[k]; !ok {
+			ev.Contexts[k] = v
+		}
+	}
+}
+
+// SetContext sets the named context of ev, such as "app" or "gpu", replacing
+// any it already has. Sentry renders the contexts it knows in panels of their
+// own, and others as tables of their values.
+func (ev *Event) SetContext(name string, values map[string]interface{}) {
+	if ev.Contexts == nil {
+		ev.Contexts = make(map[string]interface{})
+	}
+	ev.Contexts[name] = values
+}
+
+// SetOSContext sets the os context of ev to the given operating system name
+// and version, eg: "Ubuntu" and "22.04".
+func (ev *Event) SetOSContext(name, version string) {
+	ev.SetContext("os", map[string]interface{}{"name": name, "version": version})
+}
+
+// SetRuntimeContext sets the runtime context of ev to the given runtime name
+// and version. The client's IncludeRuntimeInfo fills it in with the Go version
+// for events without one.
+func (ev *Event) SetRuntimeContext(name, version string) {
+	ev.SetContext("runtime", map[string]interface{}{"name": name, "version": version})
+}
+
+// runtimeContexts returns the runtime, os and device contexts describing the
+// Go version and platform the program was built for.
+func runtimeContexts() map[string]interface{} {
+	return map[string]interface{}{
+		"runtime": map[string]interface{}{"name": "go", "version": runtime.Version()},
+		"os":      map[string]interface{}{"name": runtime.GOOS},
+		"device":  map[string]interface{}{"arch": runtime.GOARCH},
+	}
+}
+
+// envSecretKeys are parts of the names of environment variables which are
+// likely to hold secrets, and so are never included in events.
+var envSecretKeys = []string{"key", "pass", "secret", "token", "auth", "credential", "private", "dsn", "cert", "session"}
+
+// envExtra returns the environment variables selected by the client's
+// EnvAllowList and EnvDenyList, without any which may be secret.
+func (client *Client) envExtra() map[string]string {
+	return filterEnv(os.Environ(), client.EnvAllowList, client.EnvDenyList, client.SensitiveKeys)
+}
+
+// filterEnv returns the variables of environ, a list of "name=value" strings,
+// which are allowed by allow and deny and are not sensitive.
+func filterEnv(environ, allow, deny, sensitive []string) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			continue
+		}
+		name := kv[:i]
+		if len(allow) > 0 && !matchEnv(name, allow) {
+			continue
+		}
+		if matchEnv(name, deny) || isSensitive(name, envSecretKeys) || isSensitive(name, sensitive) {
+			continue
+		}
+		env[name] = kv[i+1:]
+	}
+	return env
+}
+
+// matchEnv reports whether name is one of patterns, or begins with the prefix
+// of a pattern ending in "*".
+func matchEnv(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
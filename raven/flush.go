@@ -0,0 +1,90 @@
+package raven
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// signalFlushTimeout is the time allowed for flushing the client when the
+// process receives a signal installed with InstallFlushOnSignal.
+const signalFlushTimeout = 5 * time.Second
+
+// Flush sends the events the client is holding back now rather than in the
+// background: the repeats counted within a DedupWindow, and the events waiting
+// in its spool. It returns once they have been sent, or with an error if one
+// of them couldn't be or the timeout passed first. Events which couldn't be
+// sent stay in the spool.
+func (client *Client) Flush(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client.dedup.flushAll(client)
+	if client.spool == nil {
+		return nil
+	}
+	return client.spool.flush(ctx)
+}
+
+// InstallFlushOnSignal flushes the client when the process receives one of
+// the given signals, which default to SIGINT and SIGTERM, so events aren't
+// lost when it is asked to shut down. It returns a function which removes the
+// handler.
+//
+// The handler is added alongside any the program has installed with
+// signal.Notify, which receive the signal once, as usual. Exiting is left to
+// the program: while the handler is installed the signals no longer end the
+// process by default, so a program without handlers of its own should use
+// InstallFlushOnSignalAndExit instead. A program which exits as soon as its
+// own handler receives a signal should call Flush before it does, as its
+// handler may run before the client has been flushed.
+func (client *Client) InstallFlushOnSignal(sigs ...os.Signal) (stop func()) {
+	return client.installFlushOnSignal(sigs, false)
+}
+
+// InstallFlushOnSignalAndExit is like InstallFlushOnSignal, but after flushing
+// the handler removes itself and raises the signal again, so that the default
+// handling of exiting goes ahead. It is meant for programs without handlers
+// of their own, which would otherwise receive the signal a second time.
+func (client *Client) InstallFlushOnSignalAndExit(sigs ...os.Signal) (stop func()) {
+	return client.installFlushOnSignal(sigs, true)
+}
+
+// installFlushOnSignal installs the handler of InstallFlushOnSignal, which
+// raises the signal again after flushing if reraise is set.
+func (client *Client) installFlushOnSignal(sigs []os.Signal, reraise bool) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+	go func() {
+		for {
+			select {
+			case sig := <-c:
+				client.Flush(signalFlushTimeout)
+				if !reraise {
+					continue
+				}
+				signal.Stop(c)
+				if p, err := os.FindProcess(os.Getpid()); err == nil {
+					p.Signal(sig)
+				}
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package raven
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+	events := make(chan *Event, 2)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{MinBackoff: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.CaptureMessage("during outage")
+	if err := client.Flush(time.Second); err == nil {
+		t.Error("flushing during the outage should fail")
+	}
+	if queued, _ := client.SpoolStats(); queued != 1 {
+		t.Fatalf("got %d queued events, want 1", queued)
+	}
+
+	atomic.StoreInt32(&status, 0)
+	if err := client.Flush(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Message != "during outage" {
+		t.Errorf("bad message: %q", ev.Message)
+	}
+	if queued, _ := client.SpoolStats(); queued != 0 {
+		t.Errorf("got %d queued events after flushing, want 0", queued)
+	}
+}
+
+func TestFlushDedup(t *testing.T) {
+	events := make(chan *Event, 2)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.DedupWindow = time.Hour
+
+	for i := 0; i < 3; i++ {
+		client.CaptureMessage("repeated")
+	}
+	<-events
+	if err := client.Flush(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		if n := ev.Extra["times_seen"]; n != float64(3) {
+			t.Errorf("got times_seen %v, want 3", n)
+		}
+	default:
+		t.Error("the repeats were not sent")
+	}
+}
+
+func TestInstallFlushOnSignal(t *testing.T) {
+	testInstallFlushOnSignal(t, (*Client).InstallFlushOnSignal, 1)
+}
+
+func TestInstallFlushOnSignalAndExit(t *testing.T) {
+	testInstallFlushOnSignal(t, (*Client).InstallFlushOnSignalAndExit, 2)
+}
+
+// testInstallFlushOnSignal checks that install flushes the client on SIGHUP,
+// and that a handler of the program's own receives the signal want times.
+func testInstallFlushOnSignal(t *testing.T, install func(*Client, ...os.Signal) func(), want int) {
+	events := make(chan *Event, 1)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{MinBackoff: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// The program's own handler, which also keeps a signal raised again
+	// after flushing from ending the test
+	own := make(chan os.Signal, 2)
+	signal.Notify(own, syscall.SIGHUP)
+	defer signal.Stop(own)
+
+	stop := install(client, syscall.SIGHUP)
+	defer stop()
+
+	client.CaptureMessage("before shutdown")
+	atomic.StoreInt32(&status, 0)
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("can't signal the process: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Message != "before shutdown" {
+			t.Errorf("bad message: %q", ev.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the spool was not flushed")
+	}
+	for i := 0; i < want; i++ {
+		select {
+		case <-own:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the program's handler received the signal %d times, want %d", i, want)
+		}
+	}
+	select {
+	case <-own:
+		t.Errorf("the program's handler received the signal more than %d times", want)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInstallFlushOnSignalStop(t *testing.T) {
+	client := &Client{}
+	stop := client.InstallFlushOnSignal()
+	stop()
+	stop()
+}
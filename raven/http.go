@@ -0,0 +1,103 @@
+package raven
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Http is the Sentry interface describing the HTTP request being handled when
+// an event occurred.
+type Http struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Query   string            `json:"query_string,omitempty"`
+	Cookies string            `json:"cookies,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Data    string            `json:"data,omitempty"`
+}
+
+// NewHttp creates the HTTP interface for the given request. The request body
+// is not recorded; use NewHttpWithBody for that.
+func NewHttp(req *http.Request) *Http {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	h := &Http{
+		URL:     scheme + "://" + req.Host + req.URL.Path,
+		Method:  req.Method,
+		Query:   req.URL.RawQuery,
+		Cookies: req.Header.Get("Cookie"),
+		Headers: make(map[string]string, len(req.Header)),
+	}
+	for k, v := range req.Header {
+		if k == "Cookie" {
+			continue
+		}
+		h.Headers[k] = strings.Join(v, ", ")
+	}
+	if req.RemoteAddr != "" {
+		h.Env = map[string]string{"REMOTE_ADDR": req.RemoteAddr}
+	}
+	return h
+}
+
+// WithHTTPRequest attaches the HTTP interface for req to ev, and returns ev
+// so it can be passed straight to Capture. The transaction is set to the path
+// of the request's URL, and the user's IP address to the request's remote
+// address, unless ev already has them. Headers and cookies with sensitive
+// names are filtered when the event is captured; see Client.SensitiveKeys.
+//
+// If req is nil ev is returned unchanged.
+func (ev *Event) WithHTTPRequest(req *http.Request) *Event {
+	if req == nil {
+		return ev
+	}
+	ev.Request = NewHttp(req)
+	if ev.Transaction == "" {
+		ev.Transaction = req.URL.Path
+	}
+	if ip := remoteIP(req, false); ip != "" {
+		if ev.User == nil {
+			ev.User = &User{}
+		}
+		if ev.User.IPAddress == "" {
+			ev.User.IPAddress = ip
+		}
+	}
+	return ev
+}
+
+// NewHttpWithBody is similar to NewHttp except it also records at most maxBytes
+// of the request body. The body is restored afterwards so it can still be read
+// in full by other handlers.
+//
+// Bodies of multipart requests and of requests whose length is unknown, such
+// as streaming uploads, are never recorded.
+func NewHttpWithBody(req *http.Request, maxBytes int64) *Http {
+	h := NewHttp(req)
+	if maxBytes <= 0 || req.Body == nil || req.Body == http.NoBody || req.ContentLength < 0 {
+		return h
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		return h
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBytes))
+	// Put back what was read, in front of the rest of the body
+	req.Body = &replayBody{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+	if err == nil {
+		h.Data = string(data)
+	}
+	return h
+}
+
+// replayBody is a request body which reads from Reader but closes the original body.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
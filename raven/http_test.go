@@ -0,0 +1,131 @@
+package raven
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewHttp(t *testing.T) {
+	req := httptest.NewRequest("POST", "http://example.com/users/1?verbose=1", strings.NewReader("name=bob"))
+	req.Header.Set("Cookie", "session=1234")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+
+	h := NewHttp(req)
+	if h.URL != "http://example.com/users/1" {
+		t.Errorf("bad URL: got %s", h.URL)
+	}
+	if h.Method != "POST" {
+		t.Errorf("bad method: got %s", h.Method)
+	}
+	if h.Query != "verbose=1" {
+		t.Errorf("bad query: got %s", h.Query)
+	}
+	if h.Cookies != "session=1234" {
+		t.Errorf("bad cookies: got %s", h.Cookies)
+	}
+	if _, ok := h.Headers["Cookie"]; ok {
+		t.Error("cookies should not be included in the headers")
+	}
+	if h.Headers["Accept"] != "text/html, application/json" {
+		t.Errorf("bad Accept header: got %s", h.Headers["Accept"])
+	}
+	if h.Data != "" {
+		t.Errorf("body should not be captured: got %s", h.Data)
+	}
+}
+
+func TestNewHttpWithBody(t *testing.T) {
+	body := "name=bob&email=bob@example.com"
+	req := httptest.NewRequest("POST", "http://example.com/users", strings.NewReader(body))
+
+	h := NewHttpWithBody(req, 8)
+	if h.Data != "name=bob" {
+		t.Errorf("bad data: got %q, want %q", h.Data, "name=bob")
+	}
+
+	// The body must still be readable in full
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Errorf("body was not restored: got %q, want %q", b, body)
+	}
+	if err := req.Body.Close(); err != nil {
+		t.Error(err)
+	}
+
+	// A limit larger than the body captures all of it
+	req = httptest.NewRequest("POST", "http://example.com/users", strings.NewReader(body))
+	if h := NewHttpWithBody(req, 1024); h.Data != body {
+		t.Errorf("bad data: got %q, want %q", h.Data, body)
+	}
+}
+
+func TestNewHttpWithBodySkipped(t *testing.T) {
+	multipart := httptest.NewRequest("POST", "http://example.com/upload", strings.NewReader("--boundary"))
+	multipart.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+
+	streaming := httptest.NewRequest("POST", "http://example.com/upload", strings.NewReader("chunk"))
+	streaming.ContentLength = -1
+
+	for _, req := range []*http.Request{multipart, streaming} {
+		if h := NewHttpWithBody(req, 1024); h.Data != "" {
+			t.Errorf("body should not be captured: got %q", h.Data)
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		if len(b) == 0 {
+			t.Error("body should be left untouched")
+		}
+	}
+}
+
+func TestWithHTTPRequest(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	req := httptest.NewRequest("GET", "http://example.com/users/42?token=abc&page=2", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("Cookie", "session=xyz; theme=dark")
+
+	ev := (&Event{Message: "handler failed", User: &User{Id: "42"}}).WithHTTPRequest(req)
+	if err := client.Capture(ev); err != nil {
+		t.Fatal(err)
+	}
+	sent := <-events
+	if sent.Transaction != "/users/42" {
+		t.Errorf("got transaction %q, want /users/42", sent.Transaction)
+	}
+	if sent.User == nil || sent.User.Id != "42" || sent.User.IPAddress != "192.0.2.1" {
+		t.Errorf("bad user: %+v", sent.User)
+	}
+	h := sent.Request
+	if h == nil || h.URL != "http://example.com/users/42" || h.Method != "GET" {
+		t.Fatalf("bad request: %+v", h)
+	}
+	if h.Headers["Authorization"] != filtered || h.Headers["Accept"] != "text/html" {
+		t.Errorf("bad headers: %v", h.Headers)
+	}
+	if h.Query != "token="+filtered+"&page=2" || h.Cookies != "session="+filtered+"; theme=dark" {
+		t.Errorf("bad query %q or cookies %q", h.Query, h.Cookies)
+	}
+
+	// A transaction already set is kept
+	ev = (&Event{Transaction: "GET /users/:id"}).WithHTTPRequest(req)
+	if ev.Transaction != "GET /users/:id" {
+		t.Errorf("got transaction %q, want GET /users/:id", ev.Transaction)
+	}
+
+	ev = &Event{Message: "no request"}
+	if got := ev.WithHTTPRequest(nil); got != ev || got.Request != nil || got.User != nil || got.Transaction != "" {
+		t.Errorf("a nil request changed the event: %+v", got)
+	}
+}
@@ -0,0 +1,122 @@
+package raven
+
+import (
+	"context"
+	"sync"
+)
+
+// A Hub pairs a client with a stack of scopes. The scope on top of the stack
+// is applied to the events captured through the hub.
+//
+// A Hub is safe for concurrent use, but since goroutines share its stack each
+// goroutine or request should usually have its own hub, made with Clone.
+type Hub struct {
+	mu     sync.RWMutex
+	client *Client
+	stack  []*Scope
+}
+
+// NewHub creates a hub for client whose stack holds scope. If scope is nil an
+// empty scope is used.
+func NewHub(client *Client, scope *Scope) *Hub {
+	if scope == nil {
+		scope = NewScope()
+	}
+	return &Hub{client: client, stack: []*Scope{scope}}
+}
+
+// Client returns the hub's client.
+func (h *Hub) Client() *Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.client
+}
+
+// BindClient replaces the hub's client.
+func (h *Hub) BindClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.client = client
+}
+
+// Scope returns the scope on top of the hub's stack.
+func (h *Hub) Scope() *Scope {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.stack[len(h.stack)-1]
+}
+
+// PushScope pushes a copy of the current scope onto the stack and returns it.
+// Changes to it are discarded by the matching PopScope.
+func (h *Hub) PushScope() *Scope {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	scope := h.stack[len(h.stack)-1].Clone()
+	h.stack = append(h.stack, scope)
+	return scope
+}
+
+// PopScope removes the scope on top of the stack. The bottom scope is never
+// removed, so it reports whether there was a scope to pop.
+func (h *Hub) PopScope() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.stack) == 1 {
+		return false
+	}
+	h.stack[len(h.stack)-1] = nil
+	h.stack = h.stack[:len(h.stack)-1]
+	return true
+}
+
+// ConfigureScope calls f with the current scope.
+func (h *Hub) ConfigureScope(f func(*Scope)) {
+	f(h.Scope())
+}
+
+// WithScope pushes a scope, calls f with it and pops it again.
+func (h *Hub) WithScope(f func(*Scope)) {
+	scope := h.PushScope()
+	defer h.PopScope()
+	f(scope)
+}
+
+// Clone returns a new hub with the same client and a copy of the current
+// scope, eg: for a request or goroutine to layer its own scopes on.
+func (h *Hub) Clone() *Hub {
+	return NewHub(h.Client(), h.Scope().Clone())
+}
+
+// Capture sends ev with the hub's client, adding the data of the current
+// scope to it.
+func (h *Hub) Capture(ev *Event) error {
+	return h.Client().CaptureWithScope(ev, h.Scope())
+}
+
+// CaptureMessage sends a message with the hub's client and returns the event
+// id, as Client.CaptureMessage does.
+func (h *Hub) CaptureMessage(message string) (string, error) {
+	return h.Client().CaptureMessageWithScope(message, h.Scope())
+}
+
+// CaptureError sends err with the hub's client and returns the event id, as
+// Client.CaptureError does.
+func (h *Hub) CaptureError(err error) (string, error) {
+	ev := h.Client().newExceptionEvent(err, nil)
+	if err := h.Capture(ev); err != nil {
+		return "", err
+	}
+	return ev.EventId, nil
+}
+
+// ContextWithHub returns a copy of ctx bound to hub. The scope of the hub is
+// applied to events sent with CaptureContext.
+func ContextWithHub(ctx context.Context, hub *Hub) context.Context {
+	return context.WithValue(ctx, hubKey, hub)
+}
+
+// HubFromContext returns the hub bound to ctx, or nil if there is none.
+func HubFromContext(ctx context.Context) *Hub {
+	hub, _ := ctx.Value(hubKey).(*Hub)
+	return hub
+}
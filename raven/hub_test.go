@@ -0,0 +1,111 @@
+package raven
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHubScopes(t *testing.T) {
+	hub := NewHub(NewDebugClient(nil), nil)
+	hub.ConfigureScope(func(scope *Scope) {
+		scope.SetTag("service", "accounts")
+	})
+
+	inner := hub.PushScope()
+	inner.SetTag("handler", "login")
+	ev := &Event{}
+	hub.Scope().apply(ev)
+	if ev.Tags["service"] != "accounts" || ev.Tags["handler"] != "login" {
+		t.Errorf("the pushed scope should extend the one below it: %v", ev.Tags)
+	}
+
+	if !hub.PopScope() {
+		t.Fatal("the pushed scope was not popped")
+	}
+	ev = &Event{}
+	hub.Scope().apply(ev)
+	if _, ok := ev.Tags["handler"]; ok {
+		t.Errorf("the popped scope's data leaked: %v", ev.Tags)
+	}
+	if hub.PopScope() {
+		t.Error("the bottom scope should not be popped")
+	}
+}
+
+func TestHubConcurrentScopes(t *testing.T) {
+	events := make(chan *Event, 20)
+	server := newRecordingServer(events)
+	defer server.Close()
+	base := NewHub(GetClient(server), nil)
+	base.ConfigureScope(func(scope *Scope) {
+		scope.SetTag("service", "accounts")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hub := base.Clone()
+			hub.ConfigureScope(func(scope *Scope) {
+				scope.SetTag("request", fmt.Sprint(i))
+			})
+			hub.WithScope(func(scope *Scope) {
+				scope.SetExtra("nested", i)
+				hub.CaptureMessage(fmt.Sprint("nested ", i))
+			})
+			hub.CaptureError(errors.New(fmt.Sprint("outer ", i)))
+		}(i)
+	}
+	wg.Wait()
+	close(events)
+
+	n := 0
+	for ev := range events {
+		n++
+		var i int
+		if _, err := fmt.Sscanf(ev.Message[strings.Index(ev.Message, " ")+1:], "%d", &i); err != nil {
+			t.Fatalf("bad message: %q", ev.Message)
+		}
+		if ev.Tags["service"] != "accounts" || ev.Tags["request"] != fmt.Sprint(i) {
+			t.Errorf("%q: bad tags: %v", ev.Message, ev.Tags)
+		}
+		_, nested := ev.Extra["nested"]
+		if strings.HasPrefix(ev.Message, "nested") != nested {
+			t.Errorf("%q: bad extra: %v", ev.Message, ev.Extra)
+		}
+	}
+	if n != 20 {
+		t.Errorf("got %d events, want 20", n)
+	}
+}
+
+func TestHubContext(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	if HubFromContext(context.Background()) != nil {
+		t.Error("a context without a hub should return nil")
+	}
+	hub := NewHub(client, nil)
+	hub.ConfigureScope(func(scope *Scope) {
+		scope.SetTag("handler", "login")
+	})
+	ctx := ContextWithHub(context.Background(), hub)
+	if HubFromContext(ctx) != hub {
+		t.Fatal("the hub was not bound to the context")
+	}
+
+	if err := client.CaptureContext(ctx, &Event{Message: "test message"}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Tags["handler"] != "login" {
+		t.Errorf("the hub's scope was not applied: %v", ev.Tags)
+	}
+}
@@ -0,0 +1,24 @@
+package raven
+
+// AddIntegration records that the named integration, eg: the http middleware,
+// is in use with the client. The integrations are reported in the sdk
+// interface of every event, which helps confirm how raven-go is set up.
+// Adding an integration more than once has no effect.
+func (client *Client) AddIntegration(name string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	for _, n := range client.integrations {
+		if n == name {
+			return
+		}
+	}
+	client.integrations = append(client.integrations, name)
+}
+
+// Integrations returns the names of the integrations added to the client, in
+// the order they were added.
+func (client *Client) Integrations() []string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return append([]string(nil), client.integrations...)
+}
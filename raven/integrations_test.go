@@ -0,0 +1,29 @@
+package raven
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestIntegrations(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	RecoveryHandler(client, http.NotFoundHandler())
+	RecoveryHandler(client, http.NotFoundHandler())
+	client.AddIntegration("custom")
+	if got, want := client.Integrations(), []string{"http", "custom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := client.CaptureMessage("test message"); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if ev.Sdk == nil || !reflect.DeepEqual(ev.Sdk.Integrations, []string{"http", "custom"}) {
+		t.Errorf("bad sdk: %+v", ev.Sdk)
+	}
+}
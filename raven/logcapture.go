@@ -0,0 +1,97 @@
+package raven
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxLogLine limits the length of the lines kept by LogCapture.
+const maxLogLine = 4096
+
+// LogCapture returns a writer which keeps the last n lines written to it, eg:
+// as a secondary output of a log.Logger. The lines are attached as breadcrumbs
+// to the next event the client captures, and are then discarded. Writes never
+// block on the network, and lines longer than 4KB are truncated.
+//
+// Only the most recent writer returned by LogCapture is attached to events.
+// The client reports "logcapture" among its integrations.
+func (client *Client) LogCapture(n int) io.Writer {
+	lc := &logCapture{client: client}
+	lc.lines.resize(n)
+	client.AddIntegration("logcapture")
+	client.mu.Lock()
+	client.logs = lc
+	client.mu.Unlock()
+	return lc
+}
+
+// logCapture is the writer returned by LogCapture.
+type logCapture struct {
+	client *Client
+
+	mu      sync.Mutex
+	lines   breadcrumbRing
+	partial []byte // the start of a line which hasn't been ended yet
+}
+
+func (lc *logCapture) Write(p []byte) (int, error) {
+	n := len(p)
+	timestamp := lc.client.now().UTC().Format(iso8601)
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			lc.partial = appendLogLine(lc.partial, p)
+			break
+		}
+		line := string(appendLogLine(lc.partial, p[:i]))
+		lc.partial = lc.partial[:0]
+		lc.lines.add(Breadcrumb{Timestamp: timestamp, Category: "log", Message: strings.TrimSuffix(line, "\r")})
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// appendLogLine appends as much of p to line as fits within maxLogLine.
+func appendLogLine(line, p []byte) []byte {
+	if room := maxLogLine - len(line); len(p) > room {
+		p = p[:room]
+	}
+	return append(line, p...)
+}
+
+// drain returns the lines kept so far, oldest first, and discards them.
+func (lc *logCapture) drain() []Breadcrumb {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lines := lc.lines.list()
+	capacity := len(lc.lines.items)
+	lc.lines = breadcrumbRing{}
+	lc.lines.resize(capacity)
+	return lines
+}
+
+// addLogBreadcrumbs adds the lines kept by the client's LogCapture writer to
+// the breadcrumbs of ev, in order of time.
+func (client *Client) addLogBreadcrumbs(ev *Event) {
+	client.mu.Lock()
+	lc := client.logs
+	client.mu.Unlock()
+	if lc == nil {
+		return
+	}
+	lines := lc.drain()
+	if len(lines) == 0 {
+		return
+	}
+	breadcrumbs := make([]Breadcrumb, 0, len(ev.Breadcrumbs)+len(lines))
+	breadcrumbs = append(append(breadcrumbs, ev.Breadcrumbs...), lines...)
+	sort.SliceStable(breadcrumbs, func(i, j int) bool {
+		return breadcrumbs[i].Timestamp < breadcrumbs[j].Timestamp
+	})
+	ev.Breadcrumbs = breadcrumbs
+}
@@ -0,0 +1,63 @@
+package raven
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLogCapture(t *testing.T) {
+	events := make(chan *Event, 2)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	logger := log.New(client.LogCapture(3), "", 0)
+	for i := 0; i < 5; i++ {
+		logger.Printf("line %d", i)
+	}
+	if _, err := client.CaptureMessage("with logs"); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if len(ev.Breadcrumbs) != 3 {
+		t.Fatalf("got %d breadcrumbs, want 3", len(ev.Breadcrumbs))
+	}
+	for i, b := range ev.Breadcrumbs {
+		if want := fmt.Sprint("line ", i+2); b.Message != want {
+			t.Errorf("breadcrumb %d: got %q, want %q", i, b.Message, want)
+		}
+		if b.Category != "log" {
+			t.Errorf("breadcrumb %d: got category %q, want log", i, b.Category)
+		}
+	}
+
+	// The lines are only attached to the next event
+	if _, err := client.CaptureMessage("without logs"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; len(ev.Breadcrumbs) != 0 {
+		t.Errorf("got %v, want no breadcrumbs", ev.Breadcrumbs)
+	}
+}
+
+func TestLogCapturePartialLines(t *testing.T) {
+	client := &Client{}
+	w := client.LogCapture(10)
+	fmt.Fprint(w, "hel")
+	fmt.Fprint(w, "lo\r\nwor")
+	fmt.Fprint(w, strings.Repeat("x", 2*maxLogLine)+"\n")
+
+	ev := &Event{}
+	client.addLogBreadcrumbs(ev)
+	if len(ev.Breadcrumbs) != 2 {
+		t.Fatalf("got %d breadcrumbs, want 2", len(ev.Breadcrumbs))
+	}
+	if msg := ev.Breadcrumbs[0].Message; msg != "hello" {
+		t.Errorf("got %q, want hello", msg)
+	}
+	if msg := ev.Breadcrumbs[1].Message; len(msg) != maxLogLine || !strings.HasPrefix(msg, "worx") {
+		t.Errorf("got a line of %d bytes, want %d starting with worx", len(msg), maxLogLine)
+	}
+}
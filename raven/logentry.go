@@ -0,0 +1,30 @@
+package raven
+
+import (
+	"fmt"
+)
+
+// LogEntry is the Sentry interface describing a message formatted from a
+// template and parameters. Sentry groups events by the template, so messages
+// which only differ in their parameters, such as ids, form a single issue.
+type LogEntry struct {
+	Message   string        `json:"message"`
+	Params    []interface{} `json:"params,omitempty"`
+	Formatted string        `json:"formatted,omitempty"`
+}
+
+// NewLogEntry creates the log entry for a message formatted by fmt.Sprintf
+// with the given format and args. Arguments other than strings, numbers and
+// booleans are sent formatted with %v.
+func NewLogEntry(format string, args ...interface{}) *LogEntry {
+	params := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			params[i] = arg
+		default:
+			params[i] = fmt.Sprint(arg)
+		}
+	}
+	return &LogEntry{Message: format, Params: params, Formatted: fmt.Sprintf(format, args...)}
+}
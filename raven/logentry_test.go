@@ -0,0 +1,45 @@
+package raven
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParameterizedMessages(t *testing.T) {
+	events := make(chan *Event, 2)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	if _, err := client.CaptureMessagef("user %s not found", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Message != "user bob not found" || ev.LogEntry != nil {
+		t.Errorf("got %q with log entry %+v, want only the message", ev.Message, ev.LogEntry)
+	}
+
+	client.ParameterizedMessages = true
+	if _, err := client.CaptureMessagef("user %s not found after %d tries: %v", "bob", 3, errors.New("timeout")); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if ev.Message != "user bob not found after 3 tries: timeout" {
+		t.Errorf("bad message: %q", ev.Message)
+	}
+	want := &LogEntry{
+		Message: "user %s not found after %d tries: %v",
+		// Numbers are decoded from JSON as float64
+		Params:    []interface{}{"bob", float64(3), "timeout"},
+		Formatted: "user bob not found after 3 tries: timeout",
+	}
+	if !reflect.DeepEqual(ev.LogEntry, want) {
+		t.Errorf("bad log entry:\n got %+v\nwant %+v", ev.LogEntry, want)
+	}
+
+	clone := ev.Clone()
+	clone.LogEntry.Params[0] = "alice"
+	if ev.LogEntry.Params[0] != "bob" {
+		t.Error("the clone shares its log entry's params")
+	}
+}
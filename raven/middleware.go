@@ -0,0 +1,141 @@
+package raven
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RecoveryHandler returns a handler which calls h and recovers from any panic
+// it raises. The panic is sent to Sentry as an unhandled exception, with the
+// request and, if h had written one, the response status. A 500 response is
+// written unless h had already started its response.
+//
+// The user's IP address is taken from the request; see Client.TrustProxyHeaders.
+// The request's context carries a transaction, so h may name it with
+// SetTransaction, eg: after matching a route. If the request has a valid
+// sentry-trace header, the context also carries the trace it continues, which
+// is set on the events of panics and of CaptureContext with the context.
+//
+// The stacks of all goroutines are attached to the event if the client's
+// PanicGoroutineDump is set. The client reports "http" among its
+// integrations.
+//
+// A panic with http.ErrAbortHandler is not captured, and is raised again so
+// the server aborts the response.
+func RecoveryHandler(client *Client, h http.Handler) http.Handler {
+	client.AddIntegration("http")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		ctx := ContextWithTransaction(req.Context(), "")
+		if t, err := ParseSentryTrace(req.Header.Get("sentry-trace")); err == nil {
+			ctx = ContextWithTrace(ctx, t)
+		}
+		req = req.WithContext(ctx)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			// Skip this function
+			ev := newPanicEvent(v, generateStacktrace(1, client.ExcludePaths), "RecoveryHandler")
+			ev.Request = NewHttp(req)
+			if client.PanicGoroutineDump {
+				ev.Attachments = []Attachment{goroutineDump(client.MaxGoroutineDumpSize)}
+			}
+			if rw.status != 0 {
+				ev.Extra = map[string]interface{}{"response.status": rw.status}
+			}
+			applyContext(req.Context(), ev)
+			if ip := remoteIP(req, client.TrustProxyHeaders); ip != "" {
+				if ev.User == nil {
+					ev.User = &User{}
+				}
+				if ev.User.IPAddress == "" {
+					ev.User.IPAddress = ip
+				}
+			}
+			client.Capture(ev)
+
+			if rw.status == 0 && !rw.hijacked {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(rw, req)
+	})
+}
+
+// remoteIP returns the IP address of the client which made req, without the
+// port. If trustProxy is set the last address in the X-Forwarded-For header is
+// used instead: the one appended by the proxy in front of the server, from
+// which it received the request. Any addresses before it were sent by the
+// client, and so could be forged.
+func remoteIP(req *http.Request, trustProxy bool) string {
+	if values := req.Header.Values("X-Forwarded-For"); trustProxy && len(values) > 0 {
+		addrs := strings.Split(values[len(values)-1], ",")
+		if ip := net.ParseIP(strings.TrimSpace(addrs[len(addrs)-1])); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// responseWriter records the status of the response written through it. It
+// passes Flush and Hijack through to the underlying ResponseWriter, and its
+// Unwrap method returns it for http.ResponseController.
+type responseWriter struct {
+	http.ResponseWriter
+	status   int // zero until the header is written
+	hijacked bool
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
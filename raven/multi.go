@@ -0,0 +1,85 @@
+package raven
+
+import (
+	"context"
+	"strings"
+)
+
+// A MultiPolicy determines when a MultiClient considers a capture successful.
+type MultiPolicy int
+
+const (
+	// AnySuccess treats a capture as successful if at least one client sent the event.
+	AnySuccess MultiPolicy = iota
+	// AllSuccess treats a capture as successful only if every client sent the event.
+	AllSuccess
+)
+
+// MultiError is returned by MultiClient when sending to one or more clients failed.
+// It holds one error for each failed send.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// MultiClient sends every captured event to several Sentry servers.
+type MultiClient struct {
+	Clients []*Client
+	Policy  MultiPolicy
+}
+
+// NewMultiClient creates a MultiClient which fans out to the given clients
+// using the AnySuccess policy.
+func NewMultiClient(clients ...*Client) *MultiClient {
+	return &MultiClient{Clients: clients}
+}
+
+// Capture sends the given event to every client.
+// Fields which are left blank are populated with default values, once, so all
+// servers receive the same event id and timestamp. The event is only encoded
+// again when a client's project, Encoder or MaxPayloadSize differs from the
+// previous one. Values truncated to fit one client's MaxPayloadSize stay
+// truncated for the clients after it.
+//
+// If any send fails the errors are collected in a MultiError, which is returned
+// according to the client's Policy.
+func (m *MultiClient) Capture(ev *Event) error {
+	if len(m.Clients) == 0 {
+		return nil
+	}
+	copyMaps(ev)
+	if err := m.Clients[0].prepare(ev); err != nil {
+		return err
+	}
+
+	var errs MultiError
+	var buf []byte
+	var project string
+	var encoder *Encoder
+	var maxSize int
+	for _, client := range m.Clients {
+		if buf == nil || client.Project != project || client.encoder() != encoder || client.MaxPayloadSize != maxSize {
+			ev.Project = client.Project
+			var err error
+			if buf, err = client.encode(ev); err != nil {
+				return err
+			}
+			project = client.Project
+			encoder = client.encoder()
+			maxSize = client.MaxPayloadSize
+		}
+		if err := client.sendEvent(context.Background(), ev, buf); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 || (m.Policy == AnySuccess && len(errs) < len(m.Clients)) {
+		return nil
+	}
+	return errs
+}
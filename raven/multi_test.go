@@ -0,0 +1,157 @@
+package raven
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newRecordingServer(events chan<- *Event) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			ev, err := DecodeEvent(req.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events <- ev
+			fmt.Fprint(w, "hello")
+		}))
+}
+
+func newFailingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+}
+
+func TestMultiClientCapture(t *testing.T) {
+	events := make(chan *Event, 3)
+	server1 := newRecordingServer(events)
+	defer server1.Close()
+	server2 := newRecordingServer(events)
+	defer server2.Close()
+
+	client1 := GetClient(server1)
+	client2 := GetClient(server2)
+	other, err := NewClient(BuildSentryDSN(server2.URL, "abcd", "efgh", "2", "/sentry/path"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMultiClient(client1, client2, other)
+	if err := m.Capture(&Event{Message: "fan out"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids, projects []string
+	for i := 0; i < 3; i++ {
+		ev := <-events
+		ids = append(ids, ev.EventId)
+		projects = append(projects, ev.Project)
+	}
+	if ids[0] != ids[1] || ids[1] != ids[2] {
+		t.Errorf("servers received different event ids: %v", ids)
+	}
+	if projects[0] != "1" || projects[1] != "1" || projects[2] != "2" {
+		t.Errorf("bad projects: got %v, want [1 1 2]", projects)
+	}
+}
+
+func TestMultiClientPolicy(t *testing.T) {
+	events := make(chan *Event, 2)
+	good := newRecordingServer(events)
+	defer good.Close()
+	bad := newFailingServer()
+	defer bad.Close()
+
+	m := NewMultiClient(GetClient(good), GetClient(bad))
+	if err := m.Capture(&Event{Message: "any"}); err != nil {
+		t.Errorf("AnySuccess: unexpected error: %s", err)
+	}
+
+	m.Policy = AllSuccess
+	err := m.Capture(&Event{Message: "all"})
+	merr, ok := err.(MultiError)
+	if !ok {
+		t.Fatalf("AllSuccess: got %v, want a MultiError", err)
+	}
+	if len(merr) != 1 {
+		t.Errorf("AllSuccess: got %d errors, want 1", len(merr))
+	}
+
+	m = NewMultiClient(GetClient(bad), GetClient(bad))
+	if err := m.Capture(&Event{Message: "none"}); err == nil {
+		t.Error("AnySuccess: expected an error when every send fails")
+	}
+}
+
+func TestLevelRouting(t *testing.T) {
+	defaults := make(chan *Event, 3)
+	defaultServer := newRecordingServer(defaults)
+	defer defaultServer.Close()
+	fatals := make(chan *Event, 3)
+	fatalServer := newRecordingServer(fatals)
+	defer fatalServer.Close()
+
+	client := GetClient(defaultServer)
+	fatalClient, err := NewClient(GetClient(fatalServer).URL.String() + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.LevelRouting = map[Severity]*Client{FATAL: fatalClient}
+
+	if err := client.Capture(&Event{Message: "fatal", Level: FATAL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CaptureMessageWithLevel(WARNING, "warning"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CaptureBatch([]*Event{{Message: "batched fatal", Level: FATAL}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"fatal", "batched fatal"} {
+		ev := <-fatals
+		if ev.Message != want || ev.Project != "2" {
+			t.Errorf("the fatal server got %q for project %s, want %q for project 2", ev.Message, ev.Project, want)
+		}
+	}
+	if ev := <-defaults; ev.Message != "warning" || ev.Project != "1" {
+		t.Errorf("the default server got %q for project %s, want warning for project 1", ev.Message, ev.Project)
+	}
+	select {
+	case ev := <-defaults:
+		t.Errorf("the default server also got %q", ev.Message)
+	case ev := <-fatals:
+		t.Errorf("the fatal server also got %q", ev.Message)
+	default:
+	}
+}
+
+func TestLevelRoutingForceLevel(t *testing.T) {
+	defaults := make(chan *Event, 1)
+	defaultServer := newRecordingServer(defaults)
+	defer defaultServer.Close()
+	infos := make(chan *Event, 1)
+	infoServer := newRecordingServer(infos)
+	defer infoServer.Close()
+
+	client := GetClient(defaultServer)
+	client.ForceLevel = INFO
+	client.LevelRouting = map[Severity]*Client{INFO: GetClient(infoServer)}
+
+	if err := client.Capture(&Event{Message: "forced", Level: ERROR}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-infos:
+		if ev.Level != INFO {
+			t.Errorf("the routed event was sent at %s, want %s", ev.Level, INFO)
+		}
+	case ev := <-defaults:
+		t.Errorf("the default server got %q", ev.Message)
+	}
+}
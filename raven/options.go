@@ -0,0 +1,102 @@
+package raven
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// An Option configures a Client created by NewClient.
+type Option func(*Client) error
+
+// WithRelease sets the release of the application, such as a version number
+// or commit hash, on every event.
+func WithRelease(release string) Option {
+	return func(client *Client) error {
+		client.Release = release
+		return nil
+	}
+}
+
+// WithEnvironment sets the environment the application is running in, such as
+// "production" or "staging", on every event.
+func WithEnvironment(environment string) Option {
+	return func(client *Client) error {
+		client.Environment = environment
+		return nil
+	}
+}
+
+// WithDefaultTags adds the given tags to every event.
+func WithDefaultTags(tags map[string]string) Option {
+	return func(client *Client) error {
+		client.DefaultTags = cloneStrings(tags)
+		return nil
+	}
+}
+
+// WithDefaultExtra adds the given extra data to every event.
+func WithDefaultExtra(extra map[string]interface{}) Option {
+	return func(client *Client) error {
+		client.DefaultExtra = cloneMap(extra)
+		return nil
+	}
+}
+
+// WithHTTPClient makes the client send events using the given http.Client
+// instead of one configured from the DSN.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) error {
+		if httpClient == nil {
+			return fmt.Errorf("the HTTP client must not be nil")
+		}
+		client.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithTransport makes the client send events through the given
+// http.RoundTripper, such as a CapturingTransport in tests. Like
+// WithHTTPClient, it replaces the http.Client configured from the DSN.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(client *Client) error {
+		if transport == nil {
+			return fmt.Errorf("the transport must not be nil")
+		}
+		client.httpClient = &http.Client{Transport: transport}
+		return nil
+	}
+}
+
+// WithStorePath sets the path of the server's store endpoint. See
+// Client.StorePath.
+func WithStorePath(storePath string) Option {
+	return func(client *Client) error {
+		if err := validateStorePath(storePath); err != nil {
+			return err
+		}
+		client.StorePath = storePath
+		return nil
+	}
+}
+
+// WithSampleRate sets the fraction of events which are sent, which must be
+// between 0 and 1.
+func WithSampleRate(rate float64) Option {
+	return func(client *Client) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("the sample rate must be between 0 and 1, not %v", rate)
+		}
+		client.SampleRate = rate
+		return nil
+	}
+}
+
+// WithTimeout sets the time allowed for connecting to the server and for each
+// request. See Client.SetTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *Client) error {
+		client.SetTimeout(timeout)
+		return nil
+	}
+}
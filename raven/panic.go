@@ -0,0 +1,87 @@
+package raven
+
+import (
+	"fmt"
+	"log"
+)
+
+// repanic raises a recovered panic again. It is replaced in tests, which
+// can't let the panic crash the process.
+var repanic = func(v interface{}) { panic(v) }
+
+// newPanicEvent returns a FATAL event for the panic v, raised at stacktrace.
+// The panic was not handled by the program, so the exception is marked as
+// unhandled with the given mechanism.
+func newPanicEvent(v interface{}, stacktrace Stacktrace, mechanism string) *Event {
+	ev := &Event{Level: FATAL}
+	if err, ok := v.(error); ok {
+		ev.Message = err.Error()
+		ev.Exceptions = NewExceptions(err, &stacktrace)
+	} else {
+		ev.Message = fmt.Sprint(v)
+		ev.Stacktrace = stacktrace
+		ev.Exceptions = []Exception{{Type: "panic", Value: ev.Message}}
+	}
+	setMechanism(ev.Exceptions, newMechanism(mechanism, false))
+	return ev
+}
+
+// crash sends ev, the event of a panic, and flushes the client since the
+// process is about to crash.
+func (client *Client) crash(ev *Event) {
+	client.Capture(ev)
+	client.Flush(signalFlushTimeout)
+}
+
+// CaptureUncaughtPanics sends a panic which would otherwise crash the process
+// to Sentry, then raises it again so the process still crashes. It must be
+// deferred directly, eg: at the top of main:
+//
+//	func main() {
+//		client, err := raven.NewClient(dsn)
+//		...
+//		defer client.CaptureUncaughtPanics()
+//		...
+//	}
+//
+// Go has no process-wide hook for panics, so only panics in the goroutine
+// which deferred CaptureUncaughtPanics are sent. A panic in any other
+// goroutine crashes the process without being sent, unless the goroutine was
+// started with Go. Fatal errors of the runtime, such as concurrent writes to
+// a map or running out of memory, can't be recovered and are never sent.
+func (client *Client) CaptureUncaughtPanics() {
+	if v := recover(); v != nil {
+		client.crash(newPanicEvent(v, generateStacktrace(0, client.ExcludePaths), "CaptureUncaughtPanics"))
+		repanic(v)
+	}
+}
+
+// Go runs f in a new goroutine. If f panics, the panic is sent to Sentry with
+// the state of the goroutine, then raised again, crashing the process as it
+// would have without Go. If the client's RecoverGoPanics is set the panic is
+// logged instead, and only the goroutine ends.
+func (client *Client) Go(f func()) {
+	go func() {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			stacktrace := generateStacktrace(0, client.ExcludePaths)
+			ev := newPanicEvent(v, stacktrace, "Go")
+			t, ok := currentGoroutine()
+			if ok {
+				t.Stacktrace = &stacktrace
+				ev.Threads = []Thread{t}
+			}
+			if client.RecoverGoPanics {
+				client.Capture(ev)
+				log.Printf("raven: recovered from a panic in goroutine %d: %v", t.Id, v)
+				return
+			}
+			client.crash(ev)
+			repanic(v)
+		}()
+		f()
+	}()
+}
@@ -0,0 +1,94 @@
+package raven
+
+import (
+	"encoding/json"
+)
+
+// truncated replaces values dropped to fit an event within MaxPayloadSize.
+const truncated = "[Truncated]"
+
+// encode encodes ev, which has been prepared, with the client's Encoder. If
+// the result is larger than MaxPayloadSize the largest of the event's extra
+// values and breadcrumbs are replaced with "[Truncated]", one at a time, until
+// it fits. The other fields are never truncated, so if the event is still too
+// large once nothing else is left it is returned as it is.
+//
+// An event with attachments is then encoded again as an envelope, holding
+// the event as it was truncated.
+func (client *Client) encode(ev *Event) ([]byte, error) {
+	buf, err := client.encodeEvent(ev)
+	if err != nil || len(ev.Attachments) == 0 {
+		return buf, err
+	}
+	return encodeEnvelope(ev, client.now())
+}
+
+// encodeEvent encodes ev with the client's Encoder, truncating it to fit
+// within MaxPayloadSize.
+func (client *Client) encodeEvent(ev *Event) ([]byte, error) {
+	buf, err := client.encoder().Encode(ev)
+	if err != nil || client.MaxPayloadSize <= 0 || len(buf) <= client.MaxPayloadSize {
+		return buf, err
+	}
+
+	// The maps and slices may be shared with the caller, so they are copied
+	// before anything is replaced.
+	extra := make(map[string]interface{}, len(ev.Extra))
+	for k, v := range ev.Extra {
+		extra[k] = v
+	}
+	ev.Extra = extra
+	ev.Breadcrumbs = append([]Breadcrumb(nil), ev.Breadcrumbs...)
+
+	for len(buf) > client.MaxPayloadSize {
+		if !truncateLargest(ev) {
+			break
+		}
+		if buf, err = client.encoder().Encode(ev); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// truncateLargest replaces the largest extra value or breadcrumb of ev, by
+// the length of its JSON, with "[Truncated]". It returns false if there was
+// nothing left to replace.
+func truncateLargest(ev *Event) bool {
+	minSize := jsonSize(truncated)
+	largest, key, index := minSize, "", -1
+	for k, v := range ev.Extra {
+		if size := jsonSize(v); size > largest {
+			largest, key, index = size, k, -1
+		}
+	}
+	for i, b := range ev.Breadcrumbs {
+		if b.Message == truncated && b.Data == nil {
+			continue
+		}
+		if size := jsonSize(b.Message) + jsonSize(b.Data); size > largest {
+			largest, key, index = size, "", i
+		}
+	}
+
+	switch {
+	case index >= 0:
+		ev.Breadcrumbs[index].Message = truncated
+		ev.Breadcrumbs[index].Data = nil
+	case largest > minSize:
+		ev.Extra[key] = truncated
+	default:
+		return false
+	}
+	return true
+}
+
+// jsonSize returns the length of the JSON encoding of v. Values which can't
+// be encoded have no size, since the event couldn't have been encoded either.
+func jsonSize(v interface{}) int {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(buf)
+}
@@ -0,0 +1,71 @@
+package raven
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// randomString returns n bytes of random base64, which doesn't compress well.
+func randomString(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return base64.StdEncoding.EncodeToString(buf)[:n]
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.MaxPayloadSize = 16 << 10
+
+	extra := map[string]interface{}{
+		"blob":  randomString(100 << 10),
+		"other": randomString(20 << 10),
+		"small": "kept",
+	}
+	ev := client.newExceptionEvent(errors.New("too big"), nil)
+	ev.Extra = extra
+	ev.Breadcrumbs = []Breadcrumb{{Message: "small"}, {Message: randomString(50 << 10)}}
+	if err := client.Capture(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := <-events
+	if sent.Message != "too big" || len(sent.Exceptions) != 1 || len(sent.Exceptions[0].Stacktrace.Frames) == 0 {
+		t.Errorf("the core fields were not kept: %+v", sent)
+	}
+	for _, k := range []string{"blob", "other"} {
+		if v := sent.Extra[k]; v != truncated {
+			t.Errorf("extra %s was not truncated", k)
+		}
+	}
+	if v := sent.Extra["small"]; v != "kept" {
+		t.Errorf("got extra small %v, want kept", v)
+	}
+	if len(sent.Breadcrumbs) != 2 || sent.Breadcrumbs[0].Message != "small" || sent.Breadcrumbs[1].Message != truncated {
+		t.Errorf("bad breadcrumbs: %+v", sent.Breadcrumbs)
+	}
+	if len(extra["blob"].(string)) != 100<<10 {
+		t.Error("the caller's extra map was modified")
+	}
+}
+
+func TestMaxPayloadSizeUnreachable(t *testing.T) {
+	client := &Client{MaxPayloadSize: 10}
+	ev := &Event{Message: randomString(1 << 10), Extra: map[string]interface{}{"a": "b"}}
+	buf, err := client.encode(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeEvent(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Message != ev.Message {
+		t.Error("the message was truncated")
+	}
+}
@@ -0,0 +1,58 @@
+package raven
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiter is a token bucket limiting the rate at which a client sends
+// events. The zero value is a full bucket.
+type rateLimiter struct {
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	dropped uint64 // accessed atomically
+}
+
+// allow reports whether an event may be sent according to the client's
+// MaxEventsPerSecond and Burst, taking a token from the bucket if so.
+func (client *Client) allow() bool {
+	rate := client.MaxEventsPerSecond
+	if rate <= 0 {
+		return true
+	}
+	burst := float64(client.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &client.limiter
+	now := client.now()
+	l.mu.Lock()
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * rate
+	}
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+	ok := l.tokens >= 1
+	if ok {
+		l.tokens--
+	}
+	l.mu.Unlock()
+
+	if !ok {
+		atomic.AddUint64(&l.dropped, 1)
+	}
+	return ok
+}
+
+// DroppedEvents returns the number of events which were not sent because
+// they exceeded the client's MaxEventsPerSecond.
+func (client *Client) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&client.limiter.dropped)
+}
@@ -0,0 +1,50 @@
+package raven
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	events := make(chan *Event, 100)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.MaxEventsPerSecond = 2
+	client.Burst = 5
+	now := time.Date(2013, 10, 17, 13, 25, 59, 0, time.UTC)
+	client.Now = func() time.Time { return now }
+
+	burst := func(n int) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := client.CaptureMessage("runaway"); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	burst(20)
+	if n := len(events); n != 5 {
+		t.Errorf("got %d events in the burst, want 5", n)
+	}
+	if n := client.DroppedEvents(); n != 15 {
+		t.Errorf("got %d dropped events, want 15", n)
+	}
+
+	// The bucket refills at the rate
+	now = now.Add(time.Second)
+	burst(10)
+	if n := len(events); n != 7 {
+		t.Errorf("got %d events after a second, want 7", n)
+	}
+	if n := client.DroppedEvents(); n != 23 {
+		t.Errorf("got %d dropped events, want 23", n)
+	}
+}
@@ -0,0 +1,32 @@
+package raven
+
+import (
+	"runtime/debug"
+)
+
+// SetReleaseFromBuildInfo sets the client's Release to the VCS revision the
+// running binary was built from, as recorded by the go command. It reports
+// whether a revision was found; the Release is left unchanged if it wasn't,
+// which is the case for binaries built with go run or outside a repository.
+func (client *Client) SetReleaseFromBuildInfo() bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+	release := releaseFromBuildInfo(info)
+	if release == "" {
+		return false
+	}
+	client.Release = release
+	return true
+}
+
+// releaseFromBuildInfo returns the vcs.revision setting of info, if any.
+func releaseFromBuildInfo(info *debug.BuildInfo) string {
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package raven
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestReleaseFromBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{Settings: []debug.BuildSetting{
+		{Key: "vcs", Value: "git"},
+		{Key: "vcs.revision", Value: "9f1c2e7d4b3a"},
+		{Key: "vcs.modified", Value: "false"},
+	}}
+	if release := releaseFromBuildInfo(info); release != "9f1c2e7d4b3a" {
+		t.Errorf("got release %q, want 9f1c2e7d4b3a", release)
+	}
+	if release := releaseFromBuildInfo(&debug.BuildInfo{}); release != "" {
+		t.Errorf("got release %q without VCS settings, want none", release)
+	}
+}
+
+func TestSetReleaseFromBuildInfo(t *testing.T) {
+	client := &Client{Release: "1.0"}
+	// Test binaries carry no VCS settings
+	if client.SetReleaseFromBuildInfo() {
+		t.Skip("the test binary has a VCS revision")
+	}
+	if client.Release != "1.0" {
+		t.Errorf("the release was changed to %q", client.Release)
+	}
+}
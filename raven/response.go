@@ -0,0 +1,138 @@
+package raven
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResponseSize limits how much of a response body is read.
+const maxResponseSize = 64 << 10
+
+type sentryResponse struct {
+	ResultId string `json:"result_id"`
+	Detail   string `json:"detail"`
+}
+
+// ServerError is returned when the Sentry server does not accept an event.
+type ServerError struct {
+	StatusCode int
+	Status     string // eg: "429 Too Many Requests"
+	Reason     string // the server's explanation from the X-Sentry-Error header, if any
+}
+
+func (e *ServerError) Error() string {
+	if e.Reason != "" {
+		return e.Status + ": " + e.Reason
+	}
+	return e.Status
+}
+
+// Temporary reports whether the server may accept the event if it is sent
+// again later: when the server is rate limiting or has failed itself.
+func (e *ServerError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// TimeoutError is returned when an event could not be sent because the
+// client's timeout, or the deadline of the context it was sent with, passed
+// before the server responded. The server may or may not have received it.
+type TimeoutError struct {
+	Timeout time.Duration // the time the request was allowed
+	Err     error         // the error of the request
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("sending the event timed out after %v: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// Response describes the server's response to an event.
+type Response struct {
+	StatusCode int
+	ResultId   string // the id the server gave the event, if it said
+
+	// Header holds the headers of the response which describe how the
+	// server is treating the client: Retry-After, and those beginning with
+	// X-Sentry- or X-RateLimit-, such as X-Sentry-Rate-Limits.
+	Header http.Header
+}
+
+// responseOf returns the Response for resp, in which the server gave the
+// event the given id.
+func responseOf(resp *http.Response, resultId string) *Response {
+	r := &Response{StatusCode: resp.StatusCode, ResultId: resultId, Header: make(http.Header)}
+	for k, v := range resp.Header {
+		if k == "Retry-After" || strings.HasPrefix(k, "X-Sentry-") || strings.HasPrefix(k, "X-Ratelimit-") {
+			r.Header[k] = append([]string(nil), v...)
+		}
+	}
+	return r
+}
+
+// CaptureDetailed sends ev like Capture, and returns the server's response to
+// it, eg: to monitor the rate limits the server reports. The response is
+// returned along with a *ServerError if the server didn't accept the event.
+// It is nil if the event wasn't sent: if it was sampled out, rate limited or
+// suppressed as a repeat, or if no response was received.
+func (client *Client) CaptureDetailed(ev *Event) (*Response, error) {
+	return client.capture(context.Background(), ev, nil)
+}
+
+// handleResponse interprets the server's response to an event. A status for
+// which isSuccess returns true, or any 2xx status if it is nil, is a success,
+// for which the id the server gave the event is returned if the body contains
+// one. Other statuses are returned as a *ServerError, explained by the
+// X-Sentry-Error header or else the detail in the body.
+func handleResponse(resp *http.Response, isSuccess func(statusCode int) bool) (string, error) {
+	// Older servers reply with an empty or non-JSON body
+	var r sentryResponse
+	json.Unmarshal(responseBody(resp), &r)
+
+	if isSuccess == nil {
+		isSuccess = isSuccessStatus
+	}
+	if !isSuccess(resp.StatusCode) {
+		reason := resp.Header.Get("X-Sentry-Error")
+		if reason == "" {
+			reason = r.Detail
+		}
+		return "", &ServerError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Reason:     reason,
+		}
+	}
+	return r.ResultId, nil
+}
+
+// isSuccessStatus reports whether statusCode is a 2xx status.
+func isSuccessStatus(statusCode int) bool {
+	return statusCode >= 200 && statusCode <= 299
+}
+
+// responseBody reads the body of resp, decompressing it if the server sent it
+// gzipped. If decompression fails the body is returned as it was received.
+func responseBody(resp *http.Response) []byte {
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return data
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return data
+	}
+	unzipped, err := ioutil.ReadAll(io.LimitReader(zr, maxResponseSize))
+	if err != nil {
+		return data
+	}
+	return unzipped
+}
@@ -0,0 +1,170 @@
+package raven
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newResponse(code int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: code,
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestHandleResponse(t *testing.T) {
+	tests := []struct {
+		code      int
+		header    http.Header
+		body      string
+		id        string
+		err       string
+		temporary bool
+	}{
+		{code: 200, body: `{"result_id": "abc123"}`, id: "abc123"},
+		{code: 200, body: "hello"},
+		{code: 202},
+		{code: 301, err: "301 Moved Permanently"},
+		{code: 400, header: http.Header{"X-Sentry-Error": {"Invalid api key"}}, err: "400 Bad Request: Invalid api key"},
+		{code: 403, body: `{"detail": "event submission rejected"}`, err: "403 Forbidden: event submission rejected"},
+		{code: 413, err: "413 Request Entity Too Large"},
+		{code: 429, err: "429 Too Many Requests", temporary: true},
+		{code: 503, err: "503 Service Unavailable", temporary: true},
+	}
+	for _, test := range tests {
+		id, err := handleResponse(newResponse(test.code, test.header, test.body), nil)
+		if id != test.id {
+			t.Errorf("%d: got id %q, want %q", test.code, id, test.id)
+		}
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %v", test.code, err)
+			}
+			continue
+		}
+		serr, ok := err.(*ServerError)
+		if !ok {
+			t.Errorf("%d: got %#v, want a *ServerError", test.code, err)
+			continue
+		}
+		if serr.StatusCode != test.code || serr.Error() != test.err || serr.Temporary() != test.temporary {
+			t.Errorf("%d: got %q (temporary %v), want %q (temporary %v)", test.code, serr, serr.Temporary(), test.err, test.temporary)
+		}
+	}
+}
+
+func gzipped(s string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.String()
+}
+
+func TestHandleGzipResponse(t *testing.T) {
+	gzipHeader := http.Header{"Content-Encoding": {"gzip"}}
+
+	id, err := handleResponse(newResponse(200, gzipHeader, gzipped(`{"result_id": "abc123"}`)), nil)
+	if err != nil || id != "abc123" {
+		t.Errorf("got %q, %v; want abc123", id, err)
+	}
+
+	_, err = handleResponse(newResponse(400, gzipHeader, gzipped(`{"detail": "invalid event"}`)), nil)
+	if err == nil || err.Error() != "400 Bad Request: invalid event" {
+		t.Errorf("got error %v, want the detail from the decompressed body", err)
+	}
+
+	// A body which isn't actually compressed is used as it is
+	id, err = handleResponse(newResponse(200, gzipHeader, `{"result_id": "abc123"}`), nil)
+	if err != nil || id != "abc123" {
+		t.Errorf("got %q, %v; want abc123", id, err)
+	}
+}
+
+func TestIsSuccess(t *testing.T) {
+	var status int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+		}))
+	defer server.Close()
+	client := GetClient(server)
+	// A server which only replies 200 or 204 to the events it accepts
+	client.IsSuccess = func(code int) bool { return code == http.StatusOK || code == http.StatusNoContent }
+
+	atomic.StoreInt32(&status, http.StatusNoContent)
+	if err := client.Capture(&Event{Message: "no content"}); err != nil {
+		t.Errorf("204 should be a success: %v", err)
+	}
+
+	atomic.StoreInt32(&status, http.StatusAccepted)
+	err := client.Capture(&Event{Message: "accepted"})
+	if serr, ok := err.(*ServerError); !ok || serr.StatusCode != http.StatusAccepted {
+		t.Errorf("got %v, want a 202 ServerError", err)
+	}
+
+	client.IsSuccess = nil
+	if err := client.Capture(&Event{Message: "accepted"}); err != nil {
+		t.Errorf("any 2xx status should be a success by default: %v", err)
+	}
+}
+
+func TestCaptureDetailed(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "41")
+			w.Header().Set("X-Sentry-Rate-Limits", "60:error:org")
+			w.Header().Set("Set-Cookie", "session=abc")
+			if status != http.StatusOK {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(status)
+				return
+			}
+			fmt.Fprint(w, `{"result_id":"fc6d8c0c43fc4630ad850ee518f1b9d0"}`)
+		}))
+	defer server.Close()
+	client := GetClient(server)
+
+	resp, err := client.CaptureDetailed(&Event{Message: "detailed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.ResultId != "fc6d8c0c43fc4630ad850ee518f1b9d0" {
+		t.Errorf("bad response: %+v", resp)
+	}
+	want := http.Header{
+		"X-Ratelimit-Remaining": {"41"},
+		"X-Sentry-Rate-Limits":  {"60:error:org"},
+	}
+	if !reflect.DeepEqual(resp.Header, want) {
+		t.Errorf("got headers %v, want %v", resp.Header, want)
+	}
+
+	status = http.StatusTooManyRequests
+	resp, err = client.CaptureDetailed(&Event{Message: "rate limited"})
+	if _, ok := err.(*ServerError); !ok {
+		t.Errorf("got error %v, want a *ServerError", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "30" {
+		t.Errorf("bad response to a rejected event: %+v", resp)
+	}
+
+	client.SampleRate = 0
+	if resp, err := client.CaptureDetailed(&Event{Message: "sampled out"}); resp != nil || err != nil {
+		t.Errorf("got %+v, %v for an event which wasn't sent", resp, err)
+	}
+}
@@ -0,0 +1,180 @@
+package raven
+
+import (
+	"context"
+	"sync"
+)
+
+// A Scope holds data which is added to the events captured with it: tags, the
+// user, extra data, the level and the fingerprint. Values set on an event take
+// precedence over those of the scope.
+//
+// A Scope is safe for concurrent use.
+type Scope struct {
+	mu          sync.Mutex
+	tags        map[string]string
+	extra       map[string]interface{}
+	user        *User
+	level       Severity
+	fingerprint []string
+}
+
+// NewScope returns an empty scope.
+func NewScope() *Scope {
+	return &Scope{}
+}
+
+// SetTag sets a tag.
+func (s *Scope) SetTag(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tags == nil {
+		s.tags = make(map[string]string)
+	}
+	s.tags[key] = value
+}
+
+// SetTags sets several tags.
+func (s *Scope) SetTags(tags map[string]string) {
+	for k, v := range tags {
+		s.SetTag(k, v)
+	}
+}
+
+// SetExtra sets a value in the extra data.
+func (s *Scope) SetExtra(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.extra == nil {
+		s.extra = make(map[string]interface{})
+	}
+	s.extra[key] = value
+}
+
+// SetUser sets the user affected by events.
+func (s *Scope) SetUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = &user
+}
+
+// SetLevel sets the level of events.
+func (s *Scope) SetLevel(level Severity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.level = level
+}
+
+// SetFingerprint sets the fingerprint which Sentry uses to group events.
+func (s *Scope) SetFingerprint(fingerprint []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fingerprint = append([]string(nil), fingerprint...)
+}
+
+// Clear removes all of the scope's data.
+func (s *Scope) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = nil
+	s.extra = nil
+	s.user = nil
+	s.level = ""
+	s.fingerprint = nil
+}
+
+// Clone returns a copy of the scope, which can be changed without affecting
+// the original.
+func (s *Scope) Clone() *Scope {
+	c := NewScope()
+	if s == nil {
+		return c
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.tags = cloneStrings(s.tags)
+	c.extra = cloneMap(s.extra)
+	if s.user != nil {
+		u := *s.user
+		c.user = &u
+	}
+	c.level = s.level
+	c.fingerprint = append([]string(nil), s.fingerprint...)
+	return c
+}
+
+// apply adds the scope's data to ev, without replacing any it already has.
+func (s *Scope) apply(ev *Event) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.tags) > 0 {
+		tags := make(map[string]string, len(s.tags)+len(ev.Tags))
+		for k, v := range s.tags {
+			tags[k] = v
+		}
+		for k, v := range ev.Tags {
+			tags[k] = v
+		}
+		ev.Tags = tags
+	}
+	if len(s.extra) > 0 {
+		addExtra(ev, cloneMap(s.extra))
+	}
+	if ev.User == nil && s.user != nil {
+		u := *s.user
+		ev.User = &u
+	}
+	if ev.Level == "" {
+		ev.Level = s.level
+	}
+	if len(ev.Fingerprint) == 0 && len(s.fingerprint) > 0 {
+		ev.Fingerprint = append([]string(nil), s.fingerprint...)
+	}
+}
+
+// currentScope returns the client's scope, or nil if it has never been
+// configured.
+func (client *Client) currentScope() *Scope {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.scope
+}
+
+// ConfigureScope calls f with the client's scope, whose data is added to every
+// event the client captures.
+func (client *Client) ConfigureScope(f func(*Scope)) {
+	client.mu.Lock()
+	if client.scope == nil {
+		client.scope = NewScope()
+	}
+	scope := client.scope
+	client.mu.Unlock()
+	f(scope)
+}
+
+// WithScope calls f with a copy of the client's scope, in which f can set data
+// for the events it captures with CaptureWithScope or CaptureMessageWithScope.
+// Changes to the copy don't affect the client's scope or other events.
+func (client *Client) WithScope(f func(*Scope)) {
+	f(client.currentScope().Clone())
+}
+
+// CaptureWithScope is like Capture, but adds the data of scope to the event
+// before that of the client's scope.
+func (client *Client) CaptureWithScope(ev *Event, scope *Scope) error {
+	_, err := client.capture(context.Background(), ev, scope)
+	return err
+}
+
+// CaptureMessageWithScope is like CaptureMessage, but adds the data of scope
+// to the event before that of the client's scope.
+func (client *Client) CaptureMessageWithScope(message string, scope *Scope) (string, error) {
+	ev := &Event{Message: message}
+	if err := client.CaptureWithScope(ev, scope); err != nil {
+		return "", err
+	}
+	return ev.EventId, nil
+}
@@ -0,0 +1,109 @@
+package raven
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScopeApply(t *testing.T) {
+	scope := NewScope()
+	scope.SetTags(map[string]string{"region": "eu", "handler": "login"})
+	scope.SetExtra("attempt", 2)
+	scope.SetUser(User{Id: "42"})
+	scope.SetLevel(WARNING)
+	scope.SetFingerprint([]string{"login-failure"})
+
+	ev := &Event{Tags: map[string]string{"handler": "signup"}}
+	scope.apply(ev)
+	if want := map[string]string{"region": "eu", "handler": "signup"}; !reflect.DeepEqual(ev.Tags, want) {
+		t.Errorf("bad tags: got %v, want %v", ev.Tags, want)
+	}
+	if ev.Extra["attempt"] != 2 || ev.User == nil || ev.User.Id != "42" || ev.Level != WARNING {
+		t.Errorf("bad event: %+v", ev)
+	}
+	if !reflect.DeepEqual(ev.Fingerprint, []string{"login-failure"}) {
+		t.Errorf("bad fingerprint: %v", ev.Fingerprint)
+	}
+
+	// Values set on the event take precedence
+	ev = &Event{Level: INFO, User: &User{Id: "7"}, Fingerprint: []string{"mine"}}
+	scope.apply(ev)
+	if ev.Level != INFO || ev.User.Id != "7" || ev.Fingerprint[0] != "mine" {
+		t.Errorf("the event's values were replaced: %+v", ev)
+	}
+
+	scope.Clear()
+	ev = &Event{}
+	scope.apply(ev)
+	if ev.Tags != nil || ev.Extra != nil || ev.User != nil || ev.Level != "" || ev.Fingerprint != nil {
+		t.Errorf("a cleared scope should add nothing: %+v", ev)
+	}
+}
+
+func TestWithScope(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.ConfigureScope(func(scope *Scope) {
+		scope.SetTag("service", "accounts")
+	})
+
+	client.WithScope(func(scope *Scope) {
+		scope.SetTag("handler", "login")
+		scope.SetLevel(WARNING)
+		if _, err := client.CaptureMessageWithScope("scoped", scope); err != nil {
+			t.Fatal(err)
+		}
+	})
+	ev := <-events
+	if ev.Tags["service"] != "accounts" || ev.Tags["handler"] != "login" || ev.Level != WARNING {
+		t.Errorf("bad scoped event: %+v", ev)
+	}
+
+	// The scope given to WithScope is isolated from the client's
+	if _, err := client.CaptureMessage("unscoped"); err != nil {
+		t.Fatal(err)
+	}
+	ev = <-events
+	if ev.Tags["service"] != "accounts" {
+		t.Errorf("the client's scope was not applied: %v", ev.Tags)
+	}
+	if _, ok := ev.Tags["handler"]; ok || ev.Level != ERROR {
+		t.Errorf("the data of the scope given to WithScope leaked: %+v", ev)
+	}
+}
+
+func TestScopeLeavesEventMaps(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.ConfigureScope(func(scope *Scope) {
+		scope.SetExtra("scoped", "x")
+		scope.SetTag("scoped", "x")
+	})
+
+	extra := map[string]interface{}{"k": "v"}
+	tags := map[string]string{"k": "v"}
+	if err := client.Capture(&Event{Message: "template", Extra: extra, Tags: tags}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.Extra["scoped"] != "x" || ev.Tags["scoped"] != "x" {
+		t.Errorf("the scope was not applied: %+v", ev)
+	}
+
+	scope := NewScope()
+	scope.SetExtra("hub", 1)
+	client.SampleRate = 0
+	if err := client.CaptureWithScope(&Event{Message: "sampled out", Extra: extra, Tags: tags}, scope); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]interface{}{"k": "v"}; !reflect.DeepEqual(extra, want) {
+		t.Errorf("the caller's extra was modified: got %v, want %v", extra, want)
+	}
+	if want := map[string]string{"k": "v"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("the caller's tags were modified: got %v, want %v", tags, want)
+	}
+}
@@ -0,0 +1,110 @@
+package raven
+
+import (
+	"net/url"
+	"strings"
+)
+
+// filtered replaces the values of sensitive keys.
+const filtered = "[Filtered]"
+
+// DefaultSensitiveKeys are the SensitiveKeys of clients created by NewClient.
+var DefaultSensitiveKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"api_key",
+	"apikey",
+	"session",
+}
+
+// scrub replaces the values of keys in ev which contain any of the client's
+// SensitiveKeys, ignoring case. The extra data, including nested maps, the
+// tags, and the headers, query string and cookies of the request are scrubbed.
+func (client *Client) scrub(ev *Event) {
+	keys := client.SensitiveKeys
+	if len(keys) == 0 {
+		return
+	}
+	// The extra data, tags and request may be shared with the caller, so
+	// they are copied first
+	ev.Extra = cloneMap(ev.Extra)
+	scrubMap(ev.Extra, keys)
+	ev.Tags = cloneStrings(ev.Tags)
+	scrubStrings(ev.Tags, keys)
+	if ev.Request != nil {
+		request := *ev.Request
+		request.Headers = cloneStrings(request.Headers)
+		ev.Request = &request
+		scrubStrings(ev.Request.Headers, keys)
+		ev.Request.Query = scrubPairs(ev.Request.Query, "&", keys)
+		ev.Request.Cookies = scrubPairs(ev.Request.Cookies, "; ", keys)
+	}
+}
+
+// isSensitive reports whether key contains any of keys, ignoring case.
+func isSensitive(key string, keys []string) bool {
+	key = strings.ToLower(key)
+	for _, k := range keys {
+		if k != "" && strings.Contains(key, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+func scrubMap(m map[string]interface{}, keys []string) {
+	for k, v := range m {
+		if isSensitive(k, keys) {
+			m[k] = filtered
+			continue
+		}
+		scrubValue(v, keys)
+	}
+}
+
+func scrubValue(v interface{}, keys []string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		scrubMap(v, keys)
+	case map[string]string:
+		scrubStrings(v, keys)
+	case []interface{}:
+		for _, e := range v {
+			scrubValue(e, keys)
+		}
+	}
+}
+
+func scrubStrings(m map[string]string, keys []string) {
+	for k := range m {
+		if isSensitive(k, keys) {
+			m[k] = filtered
+		}
+	}
+}
+
+// scrubPairs scrubs a list of key=value pairs separated by sep, such as a
+// query string, keeping their order.
+func scrubPairs(s, sep string, keys []string) string {
+	if s == "" {
+		return s
+	}
+	pairs := strings.Split(s, sep)
+	for i, pair := range pairs {
+		k := pair
+		if j := strings.Index(pair, "="); j >= 0 {
+			k = pair[:j]
+		}
+		name := k
+		if unescaped, err := url.QueryUnescape(k); err == nil {
+			name = unescaped
+		}
+		if isSensitive(name, keys) {
+			pairs[i] = k + "=" + filtered
+		}
+	}
+	return strings.Join(pairs, sep)
+}
@@ -0,0 +1,91 @@
+package raven
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	client := &Client{SensitiveKeys: DefaultSensitiveKeys}
+	nested := map[string]interface{}{"db_password": "hunter2", "host": "db1"}
+	ev := &Event{
+		Tags: map[string]string{"api_key": "abc", "region": "eu"},
+		Extra: map[string]interface{}{
+			"Authorization": "Bearer xyz",
+			"config":        nested,
+			"items":         []interface{}{map[string]string{"token": "t", "name": "n"}},
+			"count":         3,
+		},
+		Request: &Http{
+			Headers: map[string]string{"Authorization": "Basic abc", "Accept": "text/html"},
+			Query:   "next=home&access_token=abc&q",
+			Cookies: "sessionid=abc; theme=dark",
+		},
+	}
+	client.scrub(ev)
+
+	if want := map[string]string{"api_key": filtered, "region": "eu"}; !reflect.DeepEqual(ev.Tags, want) {
+		t.Errorf("bad tags: got %v, want %v", ev.Tags, want)
+	}
+	wantExtra := map[string]interface{}{
+		"Authorization": filtered,
+		"config":        map[string]interface{}{"db_password": filtered, "host": "db1"},
+		"items":         []interface{}{map[string]string{"token": filtered, "name": "n"}},
+		"count":         3,
+	}
+	if !reflect.DeepEqual(ev.Extra, wantExtra) {
+		t.Errorf("bad extra:\n got %v\nwant %v", ev.Extra, wantExtra)
+	}
+	if nested["db_password"] != "hunter2" {
+		t.Error("the caller's nested map was modified")
+	}
+	if want := map[string]string{"Authorization": filtered, "Accept": "text/html"}; !reflect.DeepEqual(ev.Request.Headers, want) {
+		t.Errorf("bad headers: got %v, want %v", ev.Request.Headers, want)
+	}
+	if want := "next=home&access_token=[Filtered]&q"; ev.Request.Query != want {
+		t.Errorf("bad query: got %q, want %q", ev.Request.Query, want)
+	}
+	if want := "sessionid=[Filtered]; theme=dark"; ev.Request.Cookies != want {
+		t.Errorf("bad cookies: got %q, want %q", ev.Request.Cookies, want)
+	}
+}
+
+func TestScrubCapture(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.SensitiveKeys = append(client.SensitiveKeys, "ssn")
+
+	ev := &Event{Message: "test message", Extra: map[string]interface{}{"user_ssn": "123", "user": "bob"}}
+	if err := client.Capture(ev); err != nil {
+		t.Fatal(err)
+	}
+	sent := <-events
+	if sent.Extra["user_ssn"] != filtered || sent.Extra["user"] != "bob" {
+		t.Errorf("bad extra: %v", sent.Extra)
+	}
+}
+
+func TestScrubCopies(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+
+	tags := map[string]string{"password": "hunter2"}
+	request := &Http{Headers: map[string]string{"Authorization": "Basic abc"}, Query: "token=abc"}
+	if err := client.Capture(&Event{Message: "test message", Tags: tags, Request: request}); err != nil {
+		t.Fatal(err)
+	}
+	sent := <-events
+	if sent.Tags["password"] != filtered || sent.Request.Headers["Authorization"] != filtered || sent.Request.Query != "token="+filtered {
+		t.Errorf("the event was not scrubbed: %v %+v", sent.Tags, sent.Request)
+	}
+	if tags["password"] != "hunter2" {
+		t.Errorf("the caller's tags were modified: %v", tags)
+	}
+	if request.Headers["Authorization"] != "Basic abc" || request.Query != "token=abc" {
+		t.Errorf("the caller's request was modified: %+v", request)
+	}
+}
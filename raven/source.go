@@ -0,0 +1,185 @@
+package raven
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceConfig configures the source context added to stacktraces: the lines
+// of code around the line of each frame.
+type SourceConfig struct {
+	// Lines is the number of lines kept before and after the line of each
+	// frame. It defaults to 5.
+	Lines int
+
+	// CacheSize is the number of files whose lines are kept in memory, so
+	// that repeated captures don't read them again. The least recently used
+	// file is evicted when it is exceeded. It defaults to 100.
+	CacheSize int
+
+	// MaxFileSize is the size in bytes of the largest file read. Larger files
+	// get no source context. It defaults to 1MB.
+	MaxFileSize int64
+
+	// ReadTimeout is the time allowed for reading a file, eg: one on a slow
+	// network filesystem. Files which take longer get no source context
+	// while they are in the cache. It defaults to 100ms.
+	ReadTimeout time.Duration
+}
+
+const (
+	defaultSourceLines       = 5
+	defaultSourceCacheSize   = 100
+	defaultSourceMaxFileSize = 1 << 20
+	defaultSourceReadTimeout = 100 * time.Millisecond
+)
+
+// EnableSourceContext makes the client add the lines of code around each
+// frame of its events' stacktraces, for files which can be read where the
+// program runs. It must not be called while the client is in use.
+func (client *Client) EnableSourceContext(config SourceConfig) {
+	client.source = newSourceCache(config)
+}
+
+// addSourceContext adds the source context of the frames of ev.
+func (client *Client) addSourceContext(ev *Event) {
+	if client.source == nil {
+		return
+	}
+	client.source.addContext(ev.Stacktrace.Frames)
+	for _, e := range ev.Exceptions {
+		if e.Stacktrace != nil {
+			client.source.addContext(e.Stacktrace.Frames)
+		}
+	}
+	for _, t := range ev.Threads {
+		if t.Stacktrace != nil {
+			client.source.addContext(t.Stacktrace.Frames)
+		}
+	}
+}
+
+// sourceCache is an LRU cache of the lines of source files.
+type sourceCache struct {
+	config   SourceConfig
+	readFile func(path string) ([]byte, error)
+
+	mu    sync.Mutex
+	files map[string]*list.Element // of *sourceFile
+	lru   list.List                // most recently used first
+}
+
+// sourceFile is the cached content of a file. Its lines are nil if the file
+// couldn't be read.
+type sourceFile struct {
+	path  string
+	lines []string
+}
+
+func newSourceCache(config SourceConfig) *sourceCache {
+	if config.Lines <= 0 {
+		config.Lines = defaultSourceLines
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = defaultSourceCacheSize
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaultSourceMaxFileSize
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = defaultSourceReadTimeout
+	}
+	return &sourceCache{config: config, readFile: ioutil.ReadFile, files: make(map[string]*list.Element)}
+}
+
+// addContext sets the context lines of each of frames whose file and line
+// can be found.
+func (c *sourceCache) addContext(frames []Frame) {
+	for i := range frames {
+		f := &frames[i]
+		if f.FilePath == "" || f.LineNumber <= 0 || f.ContextLine != "" {
+			continue
+		}
+		lines := c.lines(f.FilePath)
+		line := f.LineNumber - 1
+		if line >= len(lines) {
+			continue
+		}
+		start := line - c.config.Lines
+		if start < 0 {
+			start = 0
+		}
+		end := line + 1 + c.config.Lines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		f.PreContext = lines[start:line:line]
+		f.ContextLine = lines[line]
+		f.PostContext = lines[line+1 : end : end]
+	}
+}
+
+// lines returns the lines of the file at path, reading it if it isn't cached.
+// The slice is shared, and must not be modified.
+func (c *sourceCache) lines(path string) []string {
+	c.mu.Lock()
+	if e, ok := c.files[path]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*sourceFile).lines
+	}
+	c.mu.Unlock()
+
+	// The file is read without holding the lock, so a slow read doesn't hold
+	// up frames from other files. Two captures may read the same file at
+	// once, which is harmless.
+	lines := c.read(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.files[path]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*sourceFile).lines
+	}
+	c.files[path] = c.lru.PushFront(&sourceFile{path: path, lines: lines})
+	for c.lru.Len() > c.config.CacheSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.files, oldest.Value.(*sourceFile).path)
+	}
+	return lines
+}
+
+// read reads the lines of the file at path, or returns nil if it is too large
+// or can't be read in time.
+func (c *sourceCache) read(path string) []string {
+	result := make(chan []byte, 1)
+	go func() {
+		if info, err := os.Stat(path); err != nil || info.Size() > c.config.MaxFileSize {
+			result <- nil
+			return
+		}
+		data, err := c.readFile(path)
+		if err != nil || int64(len(data)) > c.config.MaxFileSize {
+			data = nil
+		}
+		result <- data
+	}()
+
+	timer := time.NewTimer(c.config.ReadTimeout)
+	defer timer.Stop()
+	select {
+	case data := <-result:
+		if data == nil {
+			return nil
+		}
+		return strings.Split(string(bytes.TrimSuffix(data, []byte("\n"))), "\n")
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package raven
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSourceContext(t *testing.T) {
+	events := make(chan *Event, 1)
+	server := newRecordingServer(events)
+	defer server.Close()
+	client := GetClient(server)
+	client.EnableSourceContext(SourceConfig{Lines: 2})
+
+	if _, err := client.CaptureMessage("with source"); err != nil { // the context line
+		t.Fatal(err)
+	}
+	f := (<-events).Stacktrace.Frames[0]
+	if !strings.HasSuffix(f.ContextLine, "// the context line") {
+		t.Errorf("bad context line: %q", f.ContextLine)
+	}
+	if len(f.PreContext) != 2 || !strings.Contains(f.PreContext[0], "EnableSourceContext") {
+		t.Errorf("bad pre context: %q", f.PreContext)
+	}
+	if len(f.PostContext) != 2 || !strings.Contains(f.PostContext[0], "t.Fatal(err)") {
+		t.Errorf("bad post context: %q", f.PostContext)
+	}
+}
+
+// writeSourceFiles writes n files of the given number of lines to dir and
+// returns their paths.
+func writeSourceFiles(t testing.TB, dir string, n, lines int) []string {
+	var paths []string
+	for i := 0; i < n; i++ {
+		var b strings.Builder
+		for j := 1; j <= lines; j++ {
+			fmt.Fprintf(&b, "file %d line %d\n", i, j)
+		}
+		path := filepath.Join(dir, fmt.Sprint(i, ".go"))
+		if err := ioutil.WriteFile(path, []byte(b.String()), 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// countReads makes c count the files it reads in *reads.
+func countReads(c *sourceCache, reads *int32) {
+	readFile := c.readFile
+	c.readFile = func(path string) ([]byte, error) {
+		atomic.AddInt32(reads, 1)
+		return readFile(path)
+	}
+}
+
+func TestSourceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raven-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	paths := writeSourceFiles(t, dir, 3, 20)
+
+	c := newSourceCache(SourceConfig{Lines: 1, CacheSize: 2})
+	var reads int32
+	countReads(c, &reads)
+	frames := func(paths ...string) []Frame {
+		var fs []Frame
+		for _, p := range paths {
+			fs = append(fs, Frame{FilePath: p, LineNumber: 10})
+		}
+		return fs
+	}
+
+	fs := frames(paths[0], paths[0], paths[1])
+	c.addContext(fs)
+	if reads != 2 {
+		t.Errorf("got %d reads, want 2", reads)
+	}
+	if f := fs[2]; f.ContextLine != "file 1 line 10" || f.PreContext[0] != "file 1 line 9" || f.PostContext[0] != "file 1 line 11" {
+		t.Errorf("bad context: %+v", f)
+	}
+
+	// Repeated captures from the same files use the cache
+	c.addContext(frames(paths[1], paths[0]))
+	if reads != 2 {
+		t.Errorf("got %d reads, want the files to be cached", reads)
+	}
+
+	// A third file evicts the least recently used one
+	c.addContext(frames(paths[2], paths[0]))
+	if reads != 3 {
+		t.Errorf("got %d reads, want 3", reads)
+	}
+	c.addContext(frames(paths[1]))
+	if reads != 4 {
+		t.Errorf("got %d reads, want the evicted file to be read again", reads)
+	}
+
+	// Lines beyond the end of the file get no context
+	fs = []Frame{{FilePath: paths[0], LineNumber: 21}, {FilePath: paths[0], LineNumber: 20}}
+	c.addContext(fs)
+	if fs[0].ContextLine != "" || fs[1].ContextLine != "file 0 line 20" || len(fs[1].PostContext) != 0 {
+		t.Errorf("bad context at the end of the file: %+v", fs)
+	}
+}
+
+func TestSourceCacheLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raven-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	paths := writeSourceFiles(t, dir, 2, 20)
+
+	// Files larger than MaxFileSize are not read
+	c := newSourceCache(SourceConfig{MaxFileSize: 100})
+	var reads int32
+	countReads(c, &reads)
+	fs := []Frame{{FilePath: paths[0], LineNumber: 1}, {FilePath: filepath.Join(dir, "missing.go"), LineNumber: 1}}
+	c.addContext(fs)
+	if reads != 0 || fs[0].ContextLine != "" || fs[1].ContextLine != "" {
+		t.Errorf("got %d reads and context %+v, want none", reads, fs)
+	}
+
+	// Slow reads are abandoned
+	c = newSourceCache(SourceConfig{ReadTimeout: 10 * time.Millisecond})
+	c.readFile = func(path string) ([]byte, error) {
+		time.Sleep(500 * time.Millisecond)
+		return ioutil.ReadFile(path)
+	}
+	fs = []Frame{{FilePath: paths[1], LineNumber: 1}}
+	start := time.Now()
+	c.addContext(fs)
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond || fs[0].ContextLine != "" {
+		t.Errorf("the read took %s and added %q, want it abandoned", elapsed, fs[0].ContextLine)
+	}
+}
+
+func BenchmarkSourceContext(b *testing.B) {
+	dir, err := ioutil.TempDir("", "raven-source")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	paths := writeSourceFiles(b, dir, 10, 500)
+	var frames []Frame
+	for i, p := range paths {
+		frames = append(frames, Frame{FilePath: p, LineNumber: 50 * i})
+	}
+
+	b.Run("Cached", func(b *testing.B) {
+		c := newSourceCache(SourceConfig{})
+		for i := 0; i < b.N; i++ {
+			c.addContext(append([]Frame(nil), frames...))
+		}
+	})
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			newSourceCache(SourceConfig{}).addContext(append([]Frame(nil), frames...))
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package raven
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpoolConfig configures the spool in which a client keeps events which failed
+// to send, so they can be sent again once the server is reachable. Spooled
+// events are retried one at a time, oldest first, as soon as they are queued
+// and then after each backoff. They are never held back to fill a batch, so
+// there is no flush interval to configure: a single event is sent as promptly
+// as a full spool. Client.Flush sends them without waiting for the backoff.
+type SpoolConfig struct {
+	// MaxEvents is the number of events kept. When it is exceeded the oldest
+	// event is evicted. It defaults to 100.
+	MaxEvents int
+
+	// MaxBytes limits the total size of the encoded events kept, if it is
+	// positive. When it is exceeded the oldest events are evicted.
+	MaxBytes int
+
+	// MinBackoff and MaxBackoff bound the time waited before retrying after a
+	// failure, which doubles with each consecutive failure. They default to
+	// one second and one minute.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Dir is a directory in which spooled events are also written, so they
+	// survive the process exiting. Events found there when the spool is
+	// enabled are sent again, so a short-lived program can leave its events
+	// for the next run. It is created if it doesn't exist. If Dir is empty
+	// events are only kept in memory.
+	Dir string
+}
+
+// ErrSpoolFull is given to a client's OnSendError for events evicted from its
+// spool to make room for newer ones.
+var ErrSpoolFull = errors.New("raven: event evicted from the full spool")
+
+const (
+	defaultSpoolEvents     = 100
+	defaultSpoolMinBackoff = time.Second
+	defaultSpoolMaxBackoff = time.Minute
+)
+
+// EnableSpool makes the client keep events which failed to send because the
+// server was unreachable or temporarily unable to accept them, and retry them
+// in the background until they are sent. Capture still returns the error of
+// the first attempt. Events rejected by the server are not retried.
+//
+// Each retry sends the event exactly as it was first encoded, with the same
+// id, and the same timestamp in its auth header, so a server which received
+// an earlier attempt recognizes it as the same event rather than a new one.
+//
+// EnableSpool replaces any spool enabled before, dropping the events it kept
+// in memory. It must not be called while the client is in use.
+func (client *Client) EnableSpool(config SpoolConfig) error {
+	send := func(ctx context.Context, e *spooledEvent) error {
+		return client.send(ctx, e.buf, e.timestamp)
+	}
+	failed := func(e *spooledEvent, err error) {
+		ev := e.ev
+		if ev == nil {
+			// The event was loaded from the spool's directory
+			if isEnvelope(e.buf) {
+				ev, _, _ = DecodeEnvelope(bytes.NewReader(e.buf))
+			} else {
+				ev, _ = DecodeEvent(bytes.NewReader(e.buf))
+			}
+		}
+		client.sendFailed(ev, err)
+	}
+	s, err := newSpool(config, send, failed)
+	if err != nil {
+		return err
+	}
+	if client.spool != nil {
+		client.spool.stop()
+	}
+	client.spool = s
+	return nil
+}
+
+// SpoolStats returns the number of events waiting in the client's spool to be
+// sent again, and the number which were evicted from it because it was full.
+func (client *Client) SpoolStats() (queued, evicted int) {
+	if client.spool == nil {
+		return 0, 0
+	}
+	return client.spool.stats()
+}
+
+// Close stops the client's background retrying of spooled events. Events
+// still in the spool are dropped, except for those written to its Dir.
+func (client *Client) Close() {
+	if client.spool != nil {
+		client.spool.stop()
+	}
+}
+
+// isRetryable reports whether an event which failed to send with err may be
+// accepted if it is sent again.
+func isRetryable(err error) bool {
+	var serr *ServerError
+	if errors.As(err, &serr) {
+		return serr.Temporary()
+	}
+	return true
+}
+
+// spooledEvent is an encoded event waiting to be sent again.
+type spooledEvent struct {
+	ev        *Event // the event which was encoded, unless it was loaded from a file
+	buf       []byte
+	timestamp time.Time
+	path      string // the file the event is written to, if any
+}
+
+// spoolFileSeq distinguishes the files of events spooled at the same time.
+var spoolFileSeq uint64
+
+// spool queues events and retries sending them in a background goroutine,
+// oldest first.
+type spool struct {
+	config SpoolConfig
+	send   func(context.Context, *spooledEvent) error
+	failed func(*spooledEvent, error) // called for events which are given up on
+
+	mu      sync.Mutex
+	events  []*spooledEvent
+	size    int
+	evicted int
+
+	wake     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newSpool(config SpoolConfig, send func(context.Context, *spooledEvent) error, failed func(*spooledEvent, error)) (*spool, error) {
+	if config.MaxEvents <= 0 {
+		config.MaxEvents = defaultSpoolEvents
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultSpoolMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = defaultSpoolMaxBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	s := &spool{
+		config: config,
+		send:   send,
+		failed: failed,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0700); err != nil {
+			return nil, err
+		}
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// add queues e, evicting the oldest events if the spool is over its limits.
+// The event is written to the spool's directory, if it has one.
+func (s *spool) add(e *spooledEvent) {
+	if s.config.Dir != "" && e.path == "" {
+		// If the event can't be written it is still kept in memory
+		e.path, _ = s.write(e)
+	}
+
+	var evicted []*spooledEvent
+	s.mu.Lock()
+	s.events = append(s.events, e)
+	s.size += len(e.buf)
+	for len(s.events) > 0 && s.full() {
+		evicted = append(evicted, s.drop())
+		s.evicted++
+	}
+	s.mu.Unlock()
+	for _, e := range evicted {
+		s.failed(e, ErrSpoolFull)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *spool) full() bool {
+	return len(s.events) > s.config.MaxEvents || (s.config.MaxBytes > 0 && s.size > s.config.MaxBytes)
+}
+
+// next returns the oldest event, if there is one.
+func (s *spool) next() (*spooledEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return nil, false
+	}
+	return s.events[0], true
+}
+
+// remove removes e, unless it was evicted while it was being sent.
+func (s *spool) remove(e *spooledEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) > 0 && s.events[0] == e {
+		s.drop()
+	}
+}
+
+// drop removes the oldest event and its file, and returns it. s.mu must be
+// held.
+func (s *spool) drop() *spooledEvent {
+	e := s.events[0]
+	if e.path != "" {
+		os.Remove(e.path)
+	}
+	s.size -= len(e.buf)
+	s.events[0] = nil
+	s.events = s.events[1:]
+	return e
+}
+
+// write writes e to a new file in the spool's directory and returns its path.
+// The file holds the event's timestamp in Unix seconds on the first line,
+// followed by the encoded event. Names sort in the order events were spooled.
+func (s *spool) write(e *spooledEvent) (string, error) {
+	seq := atomic.AddUint64(&spoolFileSeq, 1)
+	name := fmt.Sprintf("%020d-%d-%d.event", time.Now().UnixNano(), os.Getpid(), seq)
+	path := filepath.Join(s.config.Dir, name)
+
+	data := append([]byte(strconv.FormatInt(e.timestamp.Unix(), 10)+"\n"), e.buf...)
+	// Write to a temporary file first so a partial event is never loaded
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
+
+// load queues the events written to the spool's directory, oldest first.
+// Files which can't be read as events are removed.
+func (s *spool) load() error {
+	files, err := ioutil.ReadDir(s.config.Dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".event") {
+			continue
+		}
+		path := filepath.Join(s.config.Dir, f.Name())
+		e, err := readSpoolFile(path)
+		if err != nil {
+			os.Remove(path)
+			continue
+		}
+		s.add(e)
+	}
+	return nil
+}
+
+// readSpoolFile reads an event written by spool.write.
+func readSpoolFile(path string) (*spooledEvent, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("%s: missing timestamp", path)
+	}
+	sec, err := strconv.ParseInt(string(data[:i]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bad timestamp: %v", path, err)
+	}
+	return &spooledEvent{buf: data[i+1:], timestamp: time.Unix(sec, 0), path: path}, nil
+}
+
+func (s *spool) stats() (queued, evicted int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events), s.evicted
+}
+
+// flush sends the queued events now, oldest first, rather than waiting for the
+// background retry. It stops at the first event which fails to send with a
+// retryable error, leaving it and the events after it queued, and returns
+// that error.
+func (s *spool) flush(ctx context.Context) error {
+	for {
+		e, ok := s.next()
+		if !ok {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := s.send(ctx, e)
+		if err != nil && isRetryable(err) {
+			return err
+		}
+		s.remove(e)
+		if err != nil {
+			s.failed(e, err)
+		}
+	}
+}
+
+func (s *spool) stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *spool) run() {
+	backoff := s.config.MinBackoff
+	for {
+		e, ok := s.next()
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+
+		err := s.send(context.Background(), e)
+		if err != nil && isRetryable(err) {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-s.done:
+				timer.Stop()
+				return
+			}
+			if backoff *= 2; backoff > s.config.MaxBackoff {
+				backoff = s.config.MaxBackoff
+			}
+			continue
+		}
+		// The event was sent, or rejected for good
+		s.remove(e)
+		if err != nil {
+			s.failed(e, err)
+		}
+		backoff = s.config.MinBackoff
+	}
+}
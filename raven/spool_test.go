@@ -0,0 +1,323 @@
+package raven
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server which replies with the status in *status,
+// or records the event if it is zero.
+func newFlakyServer(events chan<- *Event, status *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if code := atomic.LoadInt32(status); code != 0 {
+				http.Error(w, "unavailable", int(code))
+				return
+			}
+			ev, err := DecodeEvent(req.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events <- ev
+			fmt.Fprint(w, "hello")
+		}))
+}
+
+func TestSpoolOutage(t *testing.T) {
+	events := make(chan *Event, 3)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{MinBackoff: 5 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := client.CaptureMessage(fmt.Sprint("during outage ", i))
+		if err == nil {
+			t.Fatal("the capture should have failed")
+		}
+		ids = append(ids, id)
+	}
+	if queued, _ := client.SpoolStats(); queued != 3 {
+		t.Errorf("got %d queued events, want 3", queued)
+	}
+
+	// The server recovers
+	atomic.StoreInt32(&status, 0)
+	for i := 0; i < 3; i++ {
+		select {
+		case ev := <-events:
+			if want := fmt.Sprint("during outage ", i); ev.Message != want {
+				t.Errorf("event %d: got %q, want %q", i, ev.Message, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d events were retried", i)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		queued, evicted := client.SpoolStats()
+		if queued == 0 && evicted == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d queued and %d evicted events, want none", queued, evicted)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSpoolRetrySameEvent(t *testing.T) {
+	type attempt struct{ id, auth string }
+	for _, dir := range []bool{false, true} {
+		attempts := make(chan attempt, 2)
+		var failures int32 = 1
+		server := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, req *http.Request) {
+				ev, err := DecodeEvent(req.Body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				attempts <- attempt{ev.EventId, req.Header.Get("X-Sentry-Auth")}
+				if atomic.AddInt32(&failures, -1) >= 0 {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprint(w, "hello")
+			}))
+		client := GetClient(server)
+		// The clock is only read when the event is first captured
+		captured := time.Date(2013, 10, 17, 11, 25, 59, 0, time.UTC)
+		client.Now = func() time.Time { return captured }
+		config := SpoolConfig{MinBackoff: 5 * time.Millisecond}
+		if dir {
+			var err error
+			if config.Dir, err = ioutil.TempDir("", "raven-spool"); err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(config.Dir)
+		}
+		if err := client.EnableSpool(config); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.CaptureMessage("retried"); err == nil {
+			t.Fatal("the first attempt should have failed")
+		}
+		client.Now = time.Now
+
+		first, second := <-attempts, <-attempts
+		if first.id == "" || first.id != second.id {
+			t.Errorf("dir %v: the retry had id %q, want %q", dir, second.id, first.id)
+		}
+		if first.auth != second.auth || !strings.Contains(first.auth, fmt.Sprint("sentry_timestamp=", captured.Unix(), ",")) {
+			t.Errorf("dir %v: the retry had auth %q, want %q", dir, second.auth, first.auth)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestSpoolSingleEvent(t *testing.T) {
+	events := make(chan *Event, 1)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.CaptureMessage("alone in the spool")
+	atomic.StoreInt32(&status, 0)
+	// The event is retried after the backoff, without waiting for others
+	select {
+	case ev := <-events:
+		if ev.Message != "alone in the spool" {
+			t.Errorf("bad message: %q", ev.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the event was not sent within a second")
+	}
+}
+
+func TestSpoolEviction(t *testing.T) {
+	events := make(chan *Event, 1)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{MaxEvents: 2, MinBackoff: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		client.CaptureMessage("during outage")
+	}
+	if queued, evicted := client.SpoolStats(); queued != 2 || evicted != 1 {
+		t.Errorf("got %d queued and %d evicted events, want 2 and 1", queued, evicted)
+	}
+}
+
+func TestSpoolRejected(t *testing.T) {
+	events := make(chan *Event, 1)
+	status := int32(http.StatusBadRequest)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := client.CaptureMessage("invalid"); err == nil {
+		t.Fatal("the capture should have failed")
+	}
+	if queued, _ := client.SpoolStats(); queued != 0 {
+		t.Errorf("events rejected by the server should not be spooled: got %d queued", queued)
+	}
+}
+
+func TestSpoolOnSendError(t *testing.T) {
+	events := make(chan *Event, 1)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+
+	failures := make(chan error, 2)
+	failed := make(chan string, 2)
+	client.OnSendError = func(ev *Event, err error) {
+		failed <- ev.Message
+		failures <- err
+	}
+	if err := client.EnableSpool(SpoolConfig{MaxEvents: 1, MinBackoff: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Spooled events are only reported once they are given up on
+	client.CaptureMessage("evicted")
+	select {
+	case msg := <-failed:
+		t.Fatalf("%s was reported while it was spooled", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+	client.CaptureMessage("rejected")
+	if msg, err := <-failed, <-failures; msg != "evicted" || err != ErrSpoolFull {
+		t.Errorf("got %s: %v, want evicted: %v", msg, err, ErrSpoolFull)
+	}
+
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	msg, err := <-failed, <-failures
+	if serr, ok := err.(*ServerError); msg != "rejected" || !ok || serr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %s: %v, want rejected with a 400 ServerError", msg, err)
+	}
+}
+
+func spoolFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestSpoolDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raven-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The first run can't reach the server before it exits
+	events := make(chan *Event, 2)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		client.CaptureMessage(fmt.Sprint("first run ", i))
+	}
+	client.Close()
+	if files := spoolFiles(t, dir); len(files) != 2 {
+		t.Fatalf("got %d spooled files, want 2", len(files))
+	}
+	// Files which aren't events are ignored, and corrupt events are removed
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("hello"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "0-corrupt.event"), []byte("garbage"), 0600)
+
+	// The next run sends them
+	var ok int32
+	server = newFlakyServer(events, &ok)
+	defer server.Close()
+	client = GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: 5 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			if want := fmt.Sprint("first run ", i); ev.Message != want {
+				t.Errorf("event %d: got %q, want %q", i, ev.Message, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d events were sent from the spool", i)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(spoolFiles(t, dir)) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sent events were not removed: %v", spoolFiles(t, dir))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		t.Errorf("other files should be left alone: %v", err)
+	}
+}
+
+func TestSpoolDirEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raven-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	events := make(chan *Event, 1)
+	status := int32(http.StatusServiceUnavailable)
+	server := newFlakyServer(events, &status)
+	defer server.Close()
+	client := GetClient(server)
+	if err := client.EnableSpool(SpoolConfig{Dir: dir, MaxEvents: 2, MinBackoff: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		client.CaptureMessage("during outage")
+	}
+	if files := spoolFiles(t, dir); len(files) != 2 {
+		t.Errorf("got %d spooled files, want 2", len(files))
+	}
+}
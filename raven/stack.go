@@ -0,0 +1,203 @@
+package raven
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// goroutineID returns the id of the calling goroutine. Go deliberately doesn't
+// expose goroutine ids, so it is read from the header of the goroutine's stack
+// and this is only a best effort: ok is false if the header's format changes.
+func goroutineID() (id uint64, ok bool) {
+	t, ok := currentGoroutine()
+	return t.Id, ok
+}
+
+// StackProvider is implemented by errors which carry the stack where they
+// were created, eg: an adapter for an error library's own stack type built
+// with FramesFromPCs or FramesFromRuntimeFrames. CaptureException sends the
+// frames of the first StackProvider in an error's chain, if it returns any,
+// instead of the stack of its caller.
+type StackProvider interface {
+	// StackFrames returns the frames of the stack, oldest call first.
+	StackFrames() []Frame
+}
+
+// FramesFromPCs converts program counters, as returned by runtime.Callers and
+// kept by many error libraries, into frames, oldest call first like the
+// frames of a Stacktrace. The program counters are innermost call first, as
+// runtime.Callers returns them.
+func FramesFromPCs(pcs []uintptr) []Frame {
+	if len(pcs) == 0 {
+		return nil
+	}
+	callers := make([]runtime.Frame, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		callers = append(callers, frame)
+		if !more {
+			break
+		}
+	}
+	return FramesFromRuntimeFrames(callers)
+}
+
+// autogenerated is the file of the wrappers generated by the compiler, eg: for
+// method values, which have no source of their own.
+const autogenerated = "<autogenerated>"
+
+// FramesFromRuntimeFrames converts frames as returned by runtime.CallersFrames,
+// innermost call first, into frames oldest call first. The functions of
+// methods are split into their package, which becomes the Module of the
+// frame, and the method, eg: "(*Server).handle". Frames of the runtime, such
+// as its panic handling, and of the compiler's wrappers are left out, and the
+// stack ends at the start of the goroutine.
+func FramesFromRuntimeFrames(frames []runtime.Frame) []Frame {
+	var result []Frame
+	for _, frame := range frames {
+		if frame.Function == "runtime.main" || frame.Function == "runtime.goexit" {
+			// Stop when reaching the start of the goroutine
+			break
+		}
+		if frame.Function == "" && frame.File == "" || strings.HasPrefix(frame.Function, "runtime.") || frame.File == autogenerated {
+			continue
+		}
+		result = append(result, newFrame(frame.Function, frame.File, frame.Line))
+	}
+	reverseFrames(result)
+	return result
+}
+
+// stacktraceText formats the stacktrace of ev, or of its outermost exception
+// if it has none, like a Go traceback, innermost call first:
+//
+//	github.com/example/app.(*Server).handle
+//		/home/user/app/server.go:21
+//	main.main
+//		/home/user/app/main.go:12
+//
+// It returns "" if there are no frames.
+func stacktraceText(ev *Event) string {
+	frames := ev.Stacktrace.Frames
+	if len(frames) == 0 && len(ev.Exceptions) > 0 {
+		if st := ev.Exceptions[len(ev.Exceptions)-1].Stacktrace; st != nil {
+			frames = st.Frames
+		}
+	}
+	var b strings.Builder
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+		if f.Module != "" {
+			b.WriteString(f.Module + ".")
+		}
+		b.WriteString(f.Function + "\n")
+		if f.FilePath != "" {
+			b.WriteString("\t" + f.FilePath + ":" + strconv.Itoa(f.LineNumber) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// FramesFromStack parses a goroutine's stack as formatted by runtime.Stack or
+// debug.Stack into frames, oldest call first like the frames of a Stacktrace.
+// It can be used to build a stacktrace in a panic
+// handler which only has the formatted trace, once the stack has unwound.
+//
+// The goroutine header is optional. If stack holds several goroutines only the
+// first is parsed. Lines which can't be parsed are skipped, so traces from
+// other Go versions or with extra fields produce as many frames as possible.
+func FramesFromStack(stack []byte) []Frame {
+	var lines []string
+	started := false
+	for _, line := range strings.Split(string(stack), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "goroutine ") {
+			if started {
+				break
+			}
+			started = true
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			if started && len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return parseFrames(lines)
+}
+
+// parseFrames parses the body of a goroutine's stack, in which each function
+// call is followed by an indented line giving its location:
+//
+//	main.main()
+//		/home/user/main.go:12 +0x1d
+//
+// Calls without a location, such as "...additional frames elided...", and
+// those of the compiler's wrappers, at "<autogenerated>", are skipped.
+// The stack lists the innermost call first, but the frames are returned oldest
+// first.
+func parseFrames(lines []string) []Frame {
+	var frames []Frame
+	var call string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") {
+			if call == "" {
+				continue
+			}
+			if filePath, lineNumber, ok := parseLocation(strings.TrimSpace(line)); ok && filePath != autogenerated {
+				frames = append(frames, newFrame(call, filePath, lineNumber))
+			}
+			call = ""
+			continue
+		}
+		call = parseCall(line)
+	}
+	reverseFrames(frames)
+	return frames
+}
+
+// parseCall returns the name of the function called on the given line, eg:
+//
+//	github.com/example/app.(*Server).handle(0xc000010000, {0x1, 0x2})
+//	created by github.com/example/app.New in goroutine 1
+func parseCall(line string) string {
+	if strings.HasPrefix(line, "created by ") {
+		line = line[len("created by "):]
+		if i := strings.Index(line, " in goroutine "); i >= 0 {
+			line = line[:i]
+		}
+		return line
+	}
+	if strings.HasSuffix(line, ")") {
+		// Remove the arguments
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// parseLocation parses the file and line number from a location such as:
+//
+//	/home/user/main.go:12 +0x1d fp=0xc000070f80 sp=0xc000070f60 pc=0x4921fd
+func parseLocation(location string) (string, int, bool) {
+	if i := strings.Index(location, " +0x"); i >= 0 {
+		location = location[:i]
+	} else if i := strings.Index(location, " fp=0x"); i >= 0 {
+		location = location[:i]
+	}
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(location[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return location[:i], line, true
+}
@@ -0,0 +1,373 @@
+package raven
+
+import (
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+var stackTests = []struct {
+	name   string
+	stack  string
+	frames []Frame // oldest call first
+}{
+	{
+		name: "go1.2",
+		stack: `goroutine 16 [running]:
+runtime.panic(0x5c2560, 0xc21000a1e0)
+	/usr/local/go/src/pkg/runtime/panic.c:266 +0xb6
+main.(*Server).handle(0xc210036000, 0x2)
+	/home/user/app/main.go:21 +0x65
+main.main()
+	/home/user/app/main.go:12
+`,
+		frames: []Frame{
+			{Filename: "main.go", LineNumber: 12, FilePath: "/home/user/app/main.go", Function: "main", Module: "main", InApp: true},
+			{Filename: "main.go", LineNumber: 21, FilePath: "/home/user/app/main.go", Function: "(*Server).handle", Module: "main", InApp: true},
+			{Filename: "runtime/panic.c", LineNumber: 266, FilePath: "/usr/local/go/src/pkg/runtime/panic.c", Function: "panic", Module: "runtime"},
+		},
+	},
+	{
+		name: "go1.21",
+		stack: `panic: oops
+
+goroutine 1 [running]:
+panic({0x4a0e60?, 0x4e9c48?})
+	/usr/local/go/src/runtime/panic.go:770 +0x132
+main.handle(...)
+	/home/user/app/main.go:21
+main.main()
+	/home/user/app/main.go:12 +0x1d
+`,
+		frames: []Frame{
+			{Filename: "main.go", LineNumber: 12, FilePath: "/home/user/app/main.go", Function: "main", Module: "main", InApp: true},
+			{Filename: "main.go", LineNumber: 21, FilePath: "/home/user/app/main.go", Function: "handle", Module: "main", InApp: true},
+			{Filename: "runtime/panic.go", LineNumber: 770, FilePath: "/usr/local/go/src/runtime/panic.go", Function: "panic"},
+		},
+	},
+	{
+		name: "GOTRACEBACK=system",
+		stack: `goroutine 5 gp=0xc000007180 m=nil [chan receive]:
+main.worker(0xc00001c0c0)
+	/home/user/app/main.go:30 +0x28 fp=0xc000070fc8 sp=0xc000070fa8 pc=0x4921fd
+...additional frames elided...
+created by main.main in goroutine 1
+	/home/user/app/main.go:15 +0x4f
+
+goroutine 1 [running]:
+main.main()
+	/home/user/app/main.go:16 +0x1d
+`,
+		frames: []Frame{
+			{Filename: "main.go", LineNumber: 15, FilePath: "/home/user/app/main.go", Function: "main", Module: "main", InApp: true},
+			{Filename: "main.go", LineNumber: 30, FilePath: "/home/user/app/main.go", Function: "worker", Module: "main", InApp: true},
+		},
+	},
+	{
+		name: "method value",
+		stack: `goroutine 1 [running]:
+main.(*Server).handle(0xc000010000)
+	/home/user/app/main.go:21 +0x65
+main.(*Server).handle-fm()
+	<autogenerated>:1 +0x25
+main.main()
+	/home/user/app/main.go:12 +0x1d
+`,
+		frames: []Frame{
+			{Filename: "main.go", LineNumber: 12, FilePath: "/home/user/app/main.go", Function: "main", Module: "main", InApp: true},
+			{Filename: "main.go", LineNumber: 21, FilePath: "/home/user/app/main.go", Function: "(*Server).handle", Module: "main", InApp: true},
+		},
+	},
+	{
+		name:  "windows",
+		stack: "main.main()\r\n\tC:/Users/user/app/main.go:12 +0x1d\r\n",
+		frames: []Frame{
+			{Filename: "main.go", LineNumber: 12, FilePath: "C:/Users/user/app/main.go", Function: "main", Module: "main", InApp: true},
+		},
+	},
+}
+
+func TestFramesFromStack(t *testing.T) {
+	defer func(old string) { goroot = old }(goroot)
+	goroot = "/usr/local/go"
+	for _, test := range stackTests {
+		frames := FramesFromStack([]byte(test.stack))
+		if len(frames) != len(test.frames) {
+			t.Errorf("%s: got %d frames, want %d: %+v", test.name, len(frames), len(test.frames), frames)
+			continue
+		}
+		for i, f := range frames {
+			if !reflect.DeepEqual(f, test.frames[i]) {
+				t.Errorf("%s: frame %d:\n got %+v\nwant %+v", test.name, i, f, test.frames[i])
+			}
+		}
+	}
+}
+
+func TestFramesFromRuntimeFrames(t *testing.T) {
+	defer func(old string) { goroot = old }(goroot)
+	goroot = "/usr/local/go"
+	frames := FramesFromRuntimeFrames([]runtime.Frame{
+		{Function: "runtime.gopanic", File: "/usr/local/go/src/runtime/panic.go", Line: 770},
+		{Function: "github.com/example/app.(*Server).handle", File: "/home/user/app/server.go", Line: 21},
+		{Function: "github.com/example/app.serve.func1", File: "/home/user/app/server.go", Line: 15},
+		{Function: "net/http.HandlerFunc.ServeHTTP", File: "/usr/local/go/src/net/http/server.go", Line: 2166},
+		{Function: "main.main", File: "/home/user/app/main.go", Line: 12},
+		{Function: "runtime.main", File: "/usr/local/go/src/runtime/proc.go", Line: 271},
+		{Function: "main.afterTheStart", File: "/home/user/app/main.go", Line: 1},
+	})
+	want := []Frame{
+		{Filename: "main.go", LineNumber: 12, FilePath: "/home/user/app/main.go", Function: "main", Module: "main", InApp: true},
+		{Filename: "net/http/server.go", LineNumber: 2166, FilePath: "/usr/local/go/src/net/http/server.go", Function: "HandlerFunc.ServeHTTP", Module: "net/http"},
+		{Filename: "server.go", LineNumber: 15, FilePath: "/home/user/app/server.go", Function: "serve.func1", Module: "github.com/example/app", InApp: true},
+		{Filename: "server.go", LineNumber: 21, FilePath: "/home/user/app/server.go", Function: "(*Server).handle", Module: "github.com/example/app", InApp: true},
+	}
+	if !reflect.DeepEqual(frames, want) {
+		t.Errorf("got frames\n%+v\nwant\n%+v", frames, want)
+	}
+	if frames := FramesFromRuntimeFrames(nil); frames != nil {
+		t.Errorf("got %+v for no frames", frames)
+	}
+}
+
+func TestFramesFromPCs(t *testing.T) {
+	var pcs []uintptr
+	stackAt(2, func() {
+		pcs = make([]uintptr, 64)
+		pcs = pcs[:runtime.Callers(1, pcs)]
+	})
+	frames := FramesFromPCs(pcs)
+	n := len(frames)
+	if n < 4 {
+		t.Fatalf("got %d frames, want at least 4: %+v", n, frames)
+	}
+	if fn := frames[n-1].Function; fn != "TestFramesFromPCs.func1" {
+		t.Errorf("the last frame should be the function calling runtime.Callers, not %s", fn)
+	}
+	for i, fn := range []string{"stackAt", "stackAt", "stackAt", "TestFramesFromPCs"} {
+		if f := frames[n-2-i]; f.Function != fn || !strings.HasSuffix(f.FilePath, "stack_test.go") {
+			t.Errorf("frame %d: got %+v, want %s", n-2-i, f, fn)
+		}
+	}
+	for _, f := range frames {
+		if f.Module == "runtime" {
+			t.Errorf("runtime frame %s was not left out", f.Function)
+		}
+	}
+	if frames := FramesFromPCs(nil); frames != nil {
+		t.Errorf("got %+v for no program counters", frames)
+	}
+}
+
+func TestFramesFromDebugStack(t *testing.T) {
+	frames := FramesFromStack(debug.Stack())
+	found := false
+	for _, f := range frames {
+		if f.Function == "TestFramesFromDebugStack" {
+			found = true
+			if f.LineNumber == 0 || !strings.HasSuffix(f.FilePath, "stack_test.go") {
+				t.Errorf("bad frame location: %+v", f)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("the test function is missing from the frames: %+v", frames)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id, ok := goroutineID()
+	if !ok || id == 0 {
+		t.Fatalf("got %d, %v", id, ok)
+	}
+	other := make(chan uint64)
+	go func() {
+		id, _ := goroutineID()
+		other <- id
+	}()
+	if o := <-other; o == id || o == 0 {
+		t.Errorf("another goroutine got id %d, this one has %d", o, id)
+	}
+}
+
+// callerStacktrace builds the stacktrace of its caller one frame at a time
+// with runtime.Caller, as generateStacktrace used to, without skipping frames.
+// The frames are reversed at the end, as generateStacktrace does.
+func callerStacktrace() Stacktrace {
+	var stacktrace Stacktrace
+	for depth := 1; ; depth++ {
+		pc, filePath, line, ok := runtime.Caller(depth)
+		if !ok {
+			break
+		}
+		name := runtime.FuncForPC(pc).Name()
+		if name == "runtime.main" || name == "runtime.goexit" {
+			break
+		}
+		if !strings.HasPrefix(name, "runtime.") {
+			stacktrace.Frames = append(stacktrace.Frames, newFrame(name, filePath, line))
+		}
+	}
+	reverseFrames(stacktrace.Frames)
+	return stacktrace
+}
+
+func TestGenerateStacktraceFrames(t *testing.T) {
+	stackAt(5, func() {
+		// Both are taken on the same line, so the frames should be identical
+		got, want := generateStacktrace(0, nil), callerStacktrace()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("frames differ from runtime.Caller:\n got %+v\nwant %+v", got.Frames, want.Frames)
+		}
+		if n := len(got.Frames); n < 7 {
+			t.Errorf("got %d frames, want at least 7", n)
+		}
+	})
+}
+
+// inlinedStacktrace is small enough to be inlined into its callers.
+func inlinedStacktrace() Stacktrace {
+	return generateStacktrace(0, nil)
+}
+
+func TestGenerateStacktraceInlined(t *testing.T) {
+	frames := inlinedStacktrace().Frames
+	n := len(frames)
+	if n < 2 {
+		t.Fatalf("got %d frames, want at least 2", n)
+	}
+	if fn := frames[n-1].Function; fn != "inlinedStacktrace" {
+		t.Errorf("the inlined function should be the last frame, not %s", fn)
+	}
+	if fn := frames[n-2].Function; fn != "TestGenerateStacktraceInlined" {
+		t.Errorf("the caller of the inlined function should precede it, not %s", fn)
+	}
+}
+
+// stackAt calls f from depth nested calls.
+//
+//go:noinline
+func stackAt(depth int, f func()) {
+	if depth == 0 {
+		f()
+		return
+	}
+	stackAt(depth-1, f)
+}
+
+func BenchmarkGenerateStacktrace(b *testing.B) {
+	b.ReportAllocs()
+	stackAt(20, func() {
+		for i := 0; i < b.N; i++ {
+			generateStacktrace(0, nil)
+		}
+	})
+}
+
+func TestSplitFuncName(t *testing.T) {
+	tests := []struct {
+		full, module, function string
+	}{
+		{"main.main", "main", "main"},
+		{"main.(*Server).handle", "main", "(*Server).handle"},
+		{"github.com/example/app.(*Server).handle", "github.com/example/app", "(*Server).handle"},
+		{"github.com/example/app.Server.String", "github.com/example/app", "Server.String"},
+		{"github.com/example/app.New", "github.com/example/app", "New"},
+		{"github.com/example/app.serve.func1", "github.com/example/app", "serve.func1"},
+		{"github.com/example/app.serve.func1.2", "github.com/example/app", "serve.func1.2"},
+		{"github.com/example/app.init.0", "github.com/example/app", "init.0"},
+		{"github.com/example/app.glob..func1", "github.com/example/app", "glob..func1"},
+		{"gopkg.in/yaml%2ev2.Unmarshal", "gopkg.in/yaml.v2", "Unmarshal"},
+		{"github.com/example/app.Map[...]", "github.com/example/app", "Map[...]"},
+		{"github.com/example/app.(*List[...]).Push", "github.com/example/app", "(*List[...]).Push"},
+		{"github.com/example/app.Map[go.shape.string,github.com/example/app/types.ID]", "github.com/example/app", "Map[go.shape.string,github.com/example/app/types.ID]"},
+		{"github.com/example/app.Map[...].func1", "github.com/example/app", "Map[...].func1"},
+		{"net/http.HandlerFunc.ServeHTTP", "net/http", "HandlerFunc.ServeHTTP"},
+		{"github.com/example/app.(*Server).handle-fm", "github.com/example/app", "(*Server).handle"},
+		{"github.com/example/app.Server.String-fm", "github.com/example/app", "Server.String"},
+		{"panic", "", "panic"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		module, function := splitFuncName(test.full)
+		if module != test.module || function != test.function {
+			t.Errorf("%q: got %q, %q; want %q, %q", test.full, module, function, test.module, test.function)
+		}
+	}
+}
+
+type namedThing struct{}
+
+func (namedThing) value() uintptr    { return funcPC(0) }
+func (*namedThing) pointer() uintptr { return funcPC(0) }
+
+// funcPC returns the program counter of its caller, or of the caller skip
+// frames above it.
+func funcPC(skip int) uintptr {
+	pc, _, _, _ := runtime.Caller(skip + 1)
+	return pc
+}
+
+func TestClosureAndMethodValueNames(t *testing.T) {
+	pkg := reflect.TypeOf(Frame{}).PkgPath()
+	var thing namedThing
+	value, pointer := thing.value, (&thing).pointer
+	var closure, nested uintptr
+	func() {
+		closure = funcPC(0)
+		func() { nested = funcPC(0) }()
+	}()
+
+	tests := []struct {
+		name, function string
+	}{
+		// The names the compiler gives method values and closures
+		{runtime.FuncForPC(reflect.ValueOf(value).Pointer()).Name(), "namedThing.value"},
+		{runtime.FuncForPC(reflect.ValueOf(pointer).Pointer()).Name(), "(*namedThing).pointer"},
+		{runtime.FuncForPC(closure).Name(), "TestClosureAndMethodValueNames.func1"},
+		{runtime.FuncForPC(nested).Name(), "TestClosureAndMethodValueNames.func1.func1"},
+		// The methods called through the method values
+		{runtime.FuncForPC(value()).Name(), "namedThing.value"},
+		{runtime.FuncForPC(pointer()).Name(), "(*namedThing).pointer"},
+	}
+	for _, test := range tests {
+		f := newFrame(test.name, "/home/user/app/main.go", 1)
+		if f.Module != pkg || f.Function != test.function {
+			t.Errorf("%s: got %q, %q; want %q, %q", test.name, f.Module, f.Function, pkg, test.function)
+		}
+	}
+}
+
+func TestRelativeFilePath(t *testing.T) {
+	defer func(old string) { goroot = old }(goroot)
+	goroot = "/usr/local/go"
+	tests := []struct {
+		filePath, want string
+	}{
+		{"/usr/local/go/src/net/http/server.go", "net/http/server.go"},
+		{"/usr/local/go/src/pkg/runtime/panic.c", "runtime/panic.c"},
+		{"/root/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", "github.com/pkg/errors/errors.go"},
+		{"/home/user/go/pkg/mod/github.com/go-chi/chi/v5@v5.0.8/middleware/recoverer.go", "github.com/go-chi/chi/v5/middleware/recoverer.go"},
+		{"/home/user/go/pkg/mod/github.com/!burnt!sushi/toml@v1.2.1/decode.go", "github.com/BurntSushi/toml/decode.go"},
+		{"/home/user/go/pkg/mod/golang.org/x/net@v0.0.0-20230101000000-abcdef123456/http2/server.go", "golang.org/x/net/http2/server.go"},
+		{"C:/Users/user/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", "github.com/pkg/errors/errors.go"},
+		{"/home/user/app/vendor/github.com/pkg/errors/errors.go", "github.com/pkg/errors/errors.go"},
+		{"github.com/pkg/errors@v0.9.1/errors.go", "github.com/pkg/errors/errors.go"},
+		{"github.com/example/app/main.go", "github.com/example/app/main.go"},
+		{"/home/user/app/main.go", "main.go"},
+		{"C:/Users/user/app/main.go", "main.go"},
+	}
+	for _, test := range tests {
+		if got := relativeFilePath(test.filePath); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.filePath, got, test.want)
+		}
+	}
+
+	// The absolute path is kept in the frame
+	f := newFrame("github.com/pkg/errors.New", "/root/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", 102)
+	if f.FilePath != "/root/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go" || f.Filename != "github.com/pkg/errors/errors.go" || f.Module != "github.com/pkg/errors" {
+		t.Errorf("bad frame: %+v", f)
+	}
+}
@@ -0,0 +1,132 @@
+package raven
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Thread is the Sentry interface describing the state of a goroutine.
+type Thread struct {
+	Id         uint64      `json:"id"`
+	Name       string      `json:"name,omitempty"`
+	Crashed    bool        `json:"crashed,omitempty"`
+	Current    bool        `json:"current,omitempty"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// threadValues is the wire format of the threads interface.
+type threadValues struct {
+	Values []Thread `json:"values"`
+}
+
+// Goroutines returns the state of every running goroutine, suitable for
+// Event.Threads. The calling goroutine is first and is marked as crashed.
+//
+// All goroutines are stopped while their stacks are collected, so this should
+// not be used on hot paths.
+func Goroutines() []Thread {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	threads := parseGoroutines(buf)
+	if len(threads) > 0 {
+		threads[0].Crashed = true
+		threads[0].Current = true
+	}
+	return threads
+}
+
+const defaultMaxGoroutineDumpSize = 512 << 10
+
+// goroutineDump returns the stacks of all goroutines as an attachment, cut off
+// after max bytes.
+func goroutineDump(max int) Attachment {
+	if max <= 0 {
+		max = defaultMaxGoroutineDumpSize
+	}
+	buf := make([]byte, max)
+	buf = buf[:runtime.Stack(buf, true)]
+	return Attachment{Filename: "goroutines.txt", ContentType: "text/plain", Bytes: buf}
+}
+
+// currentGoroutine returns the calling goroutine as a thread without its
+// stacktrace, marked as crashed.
+func currentGoroutine() (Thread, bool) {
+	var buf [128]byte
+	header := string(buf[:runtime.Stack(buf[:], false)])
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	t, ok := parseGoroutineHeader(header)
+	t.Crashed = true
+	t.Current = true
+	return t, ok
+}
+
+// parseGoroutines parses the output of runtime.Stack into threads.
+// The stack of each goroutine begins with a header such as:
+//
+//	goroutine 18 [chan receive, 2 minutes]:
+//
+// and is separated from the next by a blank line.
+func parseGoroutines(dump []byte) []Thread {
+	var threads []Thread
+	var lines []string
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		if t, ok := parseGoroutineHeader(lines[0]); ok {
+			t.Stacktrace = &Stacktrace{Frames: parseFrames(lines[1:])}
+			threads = append(threads, t)
+		}
+		lines = lines[:0]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return threads
+}
+
+// parseGoroutineHeader parses a goroutine header line into a thread named
+// after the goroutine's state. Headers printed with GOTRACEBACK=system carry
+// extra fields before the state, eg:
+//
+//	goroutine 1 gp=0xc000002380 m=0 mp=0x5c2b40 [running]:
+func parseGoroutineHeader(line string) (Thread, bool) {
+	if !strings.HasPrefix(line, "goroutine ") || !strings.HasSuffix(line, ":") {
+		return Thread{}, false
+	}
+	fields := strings.SplitN(strings.TrimSuffix(line[len("goroutine "):], ":"), " ", 2)
+	id, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return Thread{}, false
+	}
+	t := Thread{Id: id}
+	if len(fields) == 2 {
+		state := fields[1]
+		if i, j := strings.Index(state, "["), strings.LastIndex(state, "]"); i >= 0 && j > i {
+			state = state[i+1 : j]
+		}
+		t.Name = state
+	}
+	return t, true
+}
@@ -0,0 +1,121 @@
+package raven
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const sampleGoroutines = `goroutine 7 [running]:
+main.handle(0xc000010000, 0x2)
+	/home/user/app/main.go:21 +0x65
+main.main()
+	/home/user/app/main.go:12 +0x1d
+
+goroutine 18 [chan receive, 2 minutes]:
+github.com/example/app/worker.(*Pool).wait(0xc0000a2000)
+	/home/user/app/worker/pool.go:44 +0x3b
+created by github.com/example/app/worker.New in goroutine 1
+	/home/user/app/worker/pool.go:20 +0x9c
+`
+
+func TestParseGoroutines(t *testing.T) {
+	threads := parseGoroutines([]byte(sampleGoroutines))
+	if len(threads) != 2 {
+		t.Fatalf("got %d threads, want 2", len(threads))
+	}
+
+	main := threads[0]
+	if main.Id != 7 || main.Name != "running" {
+		t.Errorf("bad thread: got id %d, name %q", main.Id, main.Name)
+	}
+	if len(main.Stacktrace.Frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(main.Stacktrace.Frames))
+	}
+	f := main.Stacktrace.Frames[1]
+	if f.Function != "handle" || f.Module != "main" || f.FilePath != "/home/user/app/main.go" || f.LineNumber != 21 || f.Filename != "main.go" {
+		t.Errorf("bad frame: %+v", f)
+	}
+
+	worker := threads[1]
+	if worker.Id != 18 || worker.Name != "chan receive, 2 minutes" {
+		t.Errorf("bad thread: got id %d, name %q", worker.Id, worker.Name)
+	}
+	frames := worker.Stacktrace.Frames
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if frames[1].Module != "github.com/example/app/worker" || frames[1].Function != "(*Pool).wait" {
+		t.Errorf("bad method frame: %+v", frames[1])
+	}
+	if frames[0].Module != "github.com/example/app/worker" || frames[0].Function != "New" || frames[0].LineNumber != 20 {
+		t.Errorf("bad created by frame: %+v", frames[0])
+	}
+}
+
+func TestGoroutines(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		<-block
+	}()
+	<-started
+	defer close(block)
+
+	threads := Goroutines()
+	if len(threads) < 2 {
+		t.Fatalf("got %d threads, want at least 2", len(threads))
+	}
+	current := threads[0]
+	if !current.Crashed || !current.Current {
+		t.Errorf("the calling goroutine should be current and crashed: %+v", current)
+	}
+	found := false
+	for _, f := range current.Stacktrace.Frames {
+		if f.Function == "TestGoroutines" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the current thread does not include the test function: %v", current.Stacktrace.Frames)
+	}
+	for _, thread := range threads[1:] {
+		if thread.Crashed {
+			t.Errorf("only the current thread should be crashed: %+v", thread)
+		}
+	}
+
+	// Threads survive a round trip through the wire format
+	b, err := json.Marshal(&Event{Threads: threads})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev Event
+	if err := json.Unmarshal(b, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.Threads) != len(threads) || ev.Threads[0].Id != current.Id {
+		t.Errorf("threads did not round trip: %+v", ev.Threads)
+	}
+}
+
+func TestParseGoroutineHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		id   uint64
+		name string
+	}{
+		{"goroutine 1 [running]:", 1, "running"},
+		{"goroutine 18 [chan receive, 2 minutes]:", 18, "chan receive, 2 minutes"},
+		{"goroutine 5 gp=0xc000007180 m=nil [select]:", 5, "select"},
+	}
+	for _, test := range tests {
+		thread, ok := parseGoroutineHeader(test.line)
+		if !ok || thread.Id != test.id || thread.Name != test.name {
+			t.Errorf("%q: got %d %q (%v), want %d %q", test.line, thread.Id, thread.Name, ok, test.id, test.name)
+		}
+	}
+	if _, ok := parseGoroutineHeader("main.main()"); ok {
+		t.Error("a function line should not parse as a header")
+	}
+}
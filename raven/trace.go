@@ -0,0 +1,159 @@
+package raven
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/url"
+	"strings"
+)
+
+// Trace identifies the span of a distributed trace in which events occur, so
+// Sentry can correlate errors with the traces of requests across services.
+type Trace struct {
+	TraceID      string // 32 hex digits
+	SpanID       string // 16 hex digits
+	ParentSpanID string // the span of the service which made the request, if any
+	Sampled      *bool  // the sampling decision of the trace, if it was made
+}
+
+// NewTrace starts a new trace with random ids.
+func NewTrace() Trace {
+	return Trace{TraceID: newEventId(), SpanID: newSpanID()}
+}
+
+// ParseSentryTrace parses a sentry-trace header, of the form
+// "{trace id}-{span id}-{sampled}" where the sampling decision is optional,
+// and continues the trace it describes in a new span.
+func ParseSentryTrace(header string) (Trace, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Trace{}, fmt.Errorf("bad sentry-trace header %q", header)
+	}
+	if !isHex(parts[0], 32) || !isHex(parts[1], 16) {
+		return Trace{}, fmt.Errorf("bad sentry-trace header %q: the trace or span id is malformed", header)
+	}
+	t := Trace{TraceID: strings.ToLower(parts[0]), SpanID: newSpanID(), ParentSpanID: strings.ToLower(parts[1])}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "1":
+			sampled := true
+			t.Sampled = &sampled
+		case "0":
+			sampled := false
+			t.Sampled = &sampled
+		default:
+			return Trace{}, fmt.Errorf("bad sentry-trace header %q: the sampling decision must be 0 or 1", header)
+		}
+	}
+	return t, nil
+}
+
+// SentryTrace returns the sentry-trace header which continues t in the
+// service a request is sent to.
+func (t Trace) SentryTrace() string {
+	header := t.TraceID + "-" + t.SpanID
+	if t.Sampled != nil {
+		if *t.Sampled {
+			header += "-1"
+		} else {
+			header += "-0"
+		}
+	}
+	return header
+}
+
+// Baggage returns the baggage header which carries the client's values for t
+// to the service a request is sent to, alongside its sentry-trace header.
+func (client *Client) Baggage(t Trace) string {
+	entries := []string{"sentry-trace_id=" + url.QueryEscape(t.TraceID)}
+	add := func(key, value string) {
+		if value != "" {
+			entries = append(entries, "sentry-"+key+"="+url.QueryEscape(value))
+		}
+	}
+	add("public_key", client.PublicKey)
+	add("release", client.Release)
+	add("environment", client.Environment)
+	if t.Sampled != nil {
+		add("sampled", fmt.Sprint(*t.Sampled))
+	}
+	return strings.Join(entries, ",")
+}
+
+// ParseBaggage returns the Sentry values of a baggage header, those whose
+// keys begin with "sentry-", by their keys without the prefix. Other members
+// of the header, which belong to other vendors, are ignored.
+func ParseBaggage(header string) map[string]string {
+	values := make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		// Members may carry properties after a semicolon
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		kv := strings.SplitN(member, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		if !strings.HasPrefix(key, "sentry-") {
+			continue
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(kv[1]))
+		if err != nil {
+			continue
+		}
+		values[strings.TrimPrefix(key, "sentry-")] = value
+	}
+	return values
+}
+
+// SetTrace sets the trace context of ev, which Sentry uses to correlate it
+// with the trace.
+func (ev *Event) SetTrace(t Trace) {
+	trace := map[string]interface{}{"trace_id": t.TraceID, "span_id": t.SpanID}
+	if t.ParentSpanID != "" {
+		trace["parent_span_id"] = t.ParentSpanID
+	}
+	ev.SetContext("trace", trace)
+}
+
+// TraceID returns the id of the trace set on ev, or "" if it has none.
+func (ev *Event) TraceID() string {
+	trace, _ := ev.Contexts["trace"].(map[string]interface{})
+	id, _ := trace["trace_id"].(string)
+	return id
+}
+
+// ContextWithTrace returns a copy of ctx carrying t, which is set on events
+// sent with CaptureContext.
+func ContextWithTrace(ctx context.Context, t Trace) context.Context {
+	return context.WithValue(ctx, traceKey, t)
+}
+
+// TraceFromContext returns the trace carried by ctx, if it has one.
+func TraceFromContext(ctx context.Context) (Trace, bool) {
+	t, ok := ctx.Value(traceKey).(Trace)
+	return t, ok
+}
+
+// newSpanID returns a random span id.
+func newSpanID() string {
+	id := make([]byte, 8)
+	if _, err := io.ReadFull(randReader, id); err != nil {
+		binary.BigEndian.PutUint64(id, mathrand.Uint64())
+	}
+	return hex.EncodeToString(id)
+}
+
+// isHex reports whether s is n hex digits.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,88 @@
+package raven
+
+import (
+	"fmt"
+	"time"
+)
+
+// Severity is the level of an event or breadcrumb.
+type Severity string
+
+const (
+	DEBUG   = Severity("debug")
+	INFO    = Severity("info")
+	WARNING = Severity("warning")
+	ERROR   = Severity("error")
+	FATAL   = Severity("fatal")
+)
+
+var validLevels = map[Severity]bool{
+	DEBUG:   true,
+	INFO:    true,
+	WARNING: true,
+	ERROR:   true,
+	FATAL:   true,
+}
+
+// validPlatforms are the platforms known to Sentry.
+var validPlatforms = map[string]bool{
+	"as3":        true,
+	"c":          true,
+	"cfml":       true,
+	"cocoa":      true,
+	"csharp":     true,
+	"elixir":     true,
+	"go":         true,
+	"groovy":     true,
+	"haskell":    true,
+	"java":       true,
+	"javascript": true,
+	"native":     true,
+	"node":       true,
+	"objc":       true,
+	"other":      true,
+	"perl":       true,
+	"php":        true,
+	"python":     true,
+	"ruby":       true,
+}
+
+// A ValidationError describes a field of an event which the server would reject.
+type ValidationError struct {
+	Field  string // The name of the field in the JSON payload
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid event: %s %s", e.Field, e.Reason)
+}
+
+// Validate checks that the fields required by the server are set, and that the
+// timestamp, level and platform are ones it accepts.
+func (ev *Event) Validate() error {
+	required := []struct {
+		field, value string
+	}{
+		{"event_id", ev.EventId},
+		{"project", ev.Project},
+		{"timestamp", ev.Timestamp},
+		{"level", string(ev.Level)},
+		{"platform", ev.Platform},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return &ValidationError{r.field, "is required"}
+		}
+	}
+
+	if _, err := ev.parseTimestamp(); err != nil {
+		return &ValidationError{"timestamp", fmt.Sprintf("%q is not in the format %s or %s", ev.Timestamp, time.RFC3339, iso8601)}
+	}
+	if !validLevels[ev.Level] {
+		return &ValidationError{"level", fmt.Sprintf("%q is not one of debug, info, warning, error or fatal", ev.Level)}
+	}
+	if !validPlatforms[ev.Platform] {
+		return &ValidationError{"platform", fmt.Sprintf("%q is not a known platform", ev.Platform)}
+	}
+	return nil
+}
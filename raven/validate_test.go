@@ -0,0 +1,59 @@
+package raven
+
+import (
+	"testing"
+)
+
+func validEvent() *Event {
+	return &Event{
+		EventId:   "0123456789abcdef0123456789abcdef",
+		Project:   "1",
+		Timestamp: "2013-10-17T11:25:59",
+		Level:     ERROR,
+		Platform:  "go",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validEvent().Validate(); err != nil {
+		t.Fatalf("valid event failed validation: %s", err)
+	}
+
+	tests := []struct {
+		field  string
+		modify func(ev *Event)
+	}{
+		{"event_id", func(ev *Event) { ev.EventId = "" }},
+		{"project", func(ev *Event) { ev.Project = "" }},
+		{"timestamp", func(ev *Event) { ev.Timestamp = "" }},
+		{"timestamp", func(ev *Event) { ev.Timestamp = "yesterday" }},
+		{"level", func(ev *Event) { ev.Level = "" }},
+		{"level", func(ev *Event) { ev.Level = "warn" }},
+		{"platform", func(ev *Event) { ev.Platform = "" }},
+		{"platform", func(ev *Event) { ev.Platform = "golang" }},
+	}
+	for _, test := range tests {
+		ev := validEvent()
+		test.modify(ev)
+		err := ev.Validate()
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%+v: got %v, want a ValidationError", ev, err)
+			continue
+		}
+		if verr.Field != test.field {
+			t.Errorf("%+v: error names field %s, want %s", ev, verr.Field, test.field)
+		}
+	}
+}
+
+func TestCaptureValidates(t *testing.T) {
+	server := GetServer()
+	defer server.Close()
+	client := GetClient(server)
+
+	err := client.Capture(&Event{Message: "bad level", Level: "warn"})
+	if verr, ok := err.(*ValidationError); !ok || verr.Field != "level" {
+		t.Errorf("got %v, want a ValidationError for the level", err)
+	}
+}